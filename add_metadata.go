@@ -1,9 +1,8 @@
 package main
 
 import (
-	"flag"
 	"fmt"
-	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,40 +11,24 @@ import (
 	"github.com/qiita_export/repository"
 )
 
-func main() {
-	// 探索するファイルパスをflagで受け取る
-	var rootPath string
-	flag.StringVar(&rootPath, "dir", "", "探索するディレクトリパス")
-	flag.Parse()
-
-	if rootPath == "" {
-		fmt.Println("ディレクトリ指定は必須です")
-		os.Exit(1)
+// add-metadataコマンド
+// エクスポートしたMarkdownの末尾に記事IDとメタデータファイルへのリンクを追加する
+// 追加済みのMarkdownはスキップするため、繰り返し実行できる
+func runAddMetadata(args []string) error {
+	fs := newFlagSet("add-metadata")
+	rootPath := addDirFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
 	}
 
-	repo := repository.ArticleMetadata{}
-
-	// ファイル探索
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if d.IsDir() || !strings.HasSuffix(path, ".md") {
-			return nil
-		}
+	exported, err := repository.LoadExportedArticles(*rootPath)
+	if err != nil {
+		return err
+	}
 
-		// ファイル名（拡張子なし）とディレクトリを取得
+	for _, e := range exported {
+		path := e.MarkdownPath()
 		baseName := strings.TrimSuffix(filepath.Base(path), ".md")
-		dirPath := filepath.Dir(path)
-
-		// 対応するメタデータファイルのパスを取得
-		metadataPath := filepath.Join(dirPath, baseName+"_metadata.json")
-
-		// メタデータファイルが存在するか確認
-		if _, err := os.Stat(metadataPath); err != nil {
-			return fmt.Errorf("メタデータファイルがありません: %s", metadataPath)
-		}
 
 		// マークダウンファイルを読み込む
 		mdContent, err := os.ReadFile(path)
@@ -53,30 +36,24 @@ func main() {
 			return fmt.Errorf("マークダウンファイル読み込みエラー: %w", err)
 		}
 
-		// メタデータから記事を取得
-		article, err := repo.GetArticle(metadataPath)
-		if err != nil {
-			return err
+		metadata := createMetadataForMarkdown(e.Article)
+		if strings.Contains(string(mdContent), metadata) {
+			continue
 		}
 
 		// メタデータをマークダウンに追加
-		updatedContent := string(mdContent) + createMetadataForMarkdown(article)
+		updatedContent := string(mdContent) + metadata
 		// メタデータファイルへのリンクをマークダウンに追加
 		updatedContent += createFileLink(baseName + "_metadata.json")
 
 		// ファイル更新
-		if err = os.WriteFile(path, []byte(updatedContent), 0644); err != nil {
+		if err = writeFileAtomic(path, []byte(updatedContent), 0644); err != nil {
 			return fmt.Errorf("ファイル書き込みエラー: %w", err)
 		}
-		fmt.Printf("メタデータ追加完了 title=%s", filepath.Base(path))
-
-		return nil
-	})
-
-	if err != nil {
-		fmt.Printf("エラー: %v\n", err)
-		os.Exit(1)
+		slog.Info("メタデータを追加しました", "file", filepath.Base(path))
 	}
+
+	return nil
 }
 
 func createMetadataForMarkdown(article *models.Article) string {
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/qiita_export/internal/archiver"
+)
+
+const (
+	defaultChunkSize = 100
+)
+
+// archiveコマンド
+// -dir 直下のディレクトリを、ディレクトリ数 (-chunk) とサイズ (-max_size) の上限ごとにアーカイブにする
+func runArchive(args []string) error {
+	fs := newFlagSet("archive")
+	targetDir := addDirFlag(fs)
+	outDir := fs.String("out", "archives", "アーカイブの出力先")
+	format := fs.String("format", archiver.FormatZip, "アーカイブの形式 ("+strings.Join(archiver.Formats, ", ")+")")
+	part := fs.Int("part", 0, "指定した番号のアーカイブのみ作成する (0の場合は全て)")
+	zipPart := fs.Int("zip", 0, "非推奨: -part と同じ")
+	chunkSize := fs.Int("chunk", defaultChunkSize, "1つのアーカイブに含めるディレクトリの数, 0の場合は無制限")
+	maxSize := fs.String("max_size", "", "1つのアーカイブのサイズの上限 (例: 100MB, 500MiB), 未指定の場合は無制限. ファイル1件で上限を超える場合はエラー")
+	excludeIDs := fs.String("exclude", "", "comma-separated list of IDs to exclude")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *zipPart != 0 {
+		if *part != 0 && *part != *zipPart {
+			return fmt.Errorf("-zip and -part are conflicting, use -part only")
+		}
+		slog.Warn("-zip は非推奨です。-part を使用してください")
+		*part = *zipPart
+	}
+
+	maxBytes, err := parseByteSize(*maxSize)
+	if err != nil {
+		return fmt.Errorf("invalid -max_size: %w", err)
+	}
+	var exclude []string
+	if *excludeIDs != "" {
+		exclude = strings.Split(*excludeIDs, ",")
+	}
+
+	manifests, err := archiver.Build(archiver.Options{
+		SourceDir: *targetDir,
+		OutputDir: *outDir,
+		Format:    *format,
+		MaxDirs:   *chunkSize,
+		MaxBytes:  maxBytes,
+		Exclude:   exclude,
+		Part:      *part,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range manifests {
+		slog.Info("アーカイブを作成しました", "archive", m.Archive, "part", m.Part, "parts", m.Parts,
+			"directories", len(m.Dirs), "files", len(m.Files), "bytes", m.Size)
+	}
+	return nil
+}
+
+// 単位付きのサイズをバイト数に変換する
+// KB, MB, GBは1000倍, KiB, MiB, GiBは1024倍, 単位がない場合はバイト数とする
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+		{"B", 1},
+	}
+	scale := int64(1)
+	for _, u := range units {
+		if rest, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, scale = strings.TrimSpace(rest), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return n * scale, nil
+}
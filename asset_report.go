@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// ダウンロードに失敗したアセットの一覧を保存するファイル名
+const assetReportFileName = "asset_report.json"
+
+// ダウンロードに失敗したアセット
+type assetFailure struct {
+	ArticleID string `json:"article_id"`
+	Title     string `json:"title"`
+	Dir       string `json:"dir"`
+	URL       string `json:"url"`
+	Error     string `json:"error"`
+}
+
+// ダウンロードに失敗したアセットの一覧
+// 記事を並行して処理するため、muで保護する
+type assetReport struct {
+	mu       sync.Mutex
+	failures []assetFailure
+}
+
+func (r *assetReport) add(f assetFailure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, f)
+}
+
+// 出力ディレクトリに一覧を保存する
+// 失敗したアセットがない場合は、前回の一覧が残らないよう削除する
+func (r *assetReport) save(outputDir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := filepath.Join(outputDir, assetReportFileName)
+	if len(r.failures) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// 並行して処理した順によらず、同じ結果を保存する
+	slices.SortFunc(r.failures, func(a, b assetFailure) int {
+		return cmp.Or(cmp.Compare(a.ArticleID, b.ArticleID), cmp.Compare(a.URL, b.URL))
+	})
+
+	b, err := json.MarshalIndent(r.failures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, b, 0666); err != nil {
+		return fmt.Errorf("failed to write asset report: %w", err)
+	}
+	slog.Warn("ダウンロードに失敗したアセットの一覧を保存しました", "count", len(r.failures), "path", path)
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// フラグを解析し、設定ファイルのプロファイルの値を明示的に指定されていないフラグにセットする
+// 設定の優先順位は フラグ > 環境変数 (.envを含む) > 設定ファイル > デフォルト値
+// 設定ファイルがない場合、プロファイルを使用しない場合はnilを返す
+func parseFlags(flags *flag.FlagSet, args []string) (*models.Profile, error) {
+	flags.Parse(args)
+	if err := setupLogger(flags); err != nil {
+		return nil, err
+	}
+
+	// .envを環境変数にセットする (既存の環境変数は上書きしない)
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	path, err := findConfigFile(flags.Lookup("config").Value.String())
+	if err != nil || path == "" {
+		return nil, err
+	}
+	file, err := models.LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := flags.Lookup("profile").Value.String()
+	if name == "" {
+		name = os.Getenv(models.EnvProfileKey)
+	}
+	name, profile, err := file.Profile(name)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for key, value := range profile.FlagValues() {
+		if set[key] || flags.Lookup(key) == nil {
+			continue
+		}
+		if err := flags.Set(key, value); err != nil {
+			return nil, fmt.Errorf("profile %s: invalid %s: %w", name, key, err)
+		}
+	}
+
+	slog.Info("プロファイルを読み込みました", "profile", name, "path", path)
+	return profile, nil
+}
+
+// 設定ファイルのパス, -config, 環境変数, カレントディレクトリのデフォルトのファイルの順に探す
+// -config, 環境変数で指定したファイルが存在しない場合はエラーにする
+func findConfigFile(flagValue string) (string, error) {
+	for _, path := range []string{flagValue, os.Getenv(models.EnvConfigFileKey)} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return path, nil
+	}
+	for _, path := range models.DefaultConfigFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// 環境変数とプロファイルから設定を読み込み、検証する
+func loadConfig(profile *models.Profile) (*models.Config, error) {
+	config := models.NewConfig()
+	if profile != nil {
+		for _, key := range config.ApplyProfile(profile) {
+			slog.Warn("環境変数がプロファイルの値を上書きします", "env", key)
+		}
+		if profile.TokenCommand != "" {
+			if config.AccessToken != "" {
+				slog.Info("環境変数のアクセストークンを使用し、token_command は実行しません")
+			} else {
+				token, err := runTokenCommand(profile.TokenCommand)
+				if err != nil {
+					return nil, err
+				}
+				config.AccessToken = token
+			}
+		}
+	}
+
+	if config.AccessToken == "" || config.Domain == "" {
+		return nil, fmt.Errorf("config required: ACCESS_TOKEN, DOMAIN を環境変数 (.env) か設定ファイルのプロファイルで指定してください")
+	}
+	if err := repository.ValidateAssetHosts(append(config.AssetAllowHosts, config.AssetDenyHosts...)); err != nil {
+		return nil, fmt.Errorf("ASSET_ALLOW_HOSTS, ASSET_DENY_HOSTSが不正です: %w", err)
+	}
+	if os.Getenv("ASSET_REGEXP") != "" {
+		slog.Warn("ASSET_REGEXPは使用されなくなりました。デフォルト以外のホストのアセットはASSET_ALLOW_HOSTSで指定してください")
+	}
+
+	return config, nil
+}
+
+// token_commandを実行し、標準出力の前後の空白を除いた値をアクセストークンとする
+func runTokenCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run token_command: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token_command printed an empty token")
+	}
+	return token, nil
+}
+
+// configコマンド
+// config validate: 設定ファイルの全てのプロファイルを検証する
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("usage: qiita-export config validate [-config path]")
+	}
+	flags := newFlagSet("config validate")
+	flags.Parse(args[1:])
+	if err := setupLogger(flags); err != nil {
+		return err
+	}
+
+	path, err := findConfigFile(flags.Lookup("config").Value.String())
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("config file not found (-config, $%s, %s)", models.EnvConfigFileKey, strings.Join(models.DefaultConfigFiles, ", "))
+	}
+	file, err := models.LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if len(file.Profiles) == 0 {
+		return fmt.Errorf("%s: profiles is empty", path)
+	}
+
+	invalid := 0
+	for _, name := range file.ProfileNames() {
+		p := file.Profiles[name]
+		if err := validateProfile(&p); err != nil {
+			invalid++
+			fmt.Printf("%s: NG\n", name)
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("  %s\n", line)
+			}
+			continue
+		}
+		fmt.Printf("%s: OK\n", name)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%s: %d 件のプロファイルが不正です", path, invalid)
+	}
+	return nil
+}
+
+// プロファイルの値を検証する, token_commandは実行しない
+func validateProfile(p *models.Profile) error {
+	var errs []error
+	if p.Domain == "" {
+		errs = append(errs, errors.New("domain is required"))
+	} else if strings.Contains(p.Domain, "/") {
+		errs = append(errs, fmt.Errorf("domain %q must be a host name (e.g. example.qiita.com)", p.Domain))
+	}
+	if p.Token != "" && p.TokenCommand != "" {
+		errs = append(errs, errors.New("token and token_command are mutually exclusive"))
+	}
+	if p.BaseURL != "" {
+		if u, err := url.Parse(p.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("base_url %q must be an http(s) URL", p.BaseURL))
+		}
+	}
+	if err := repository.ValidateAssetHosts(append(p.AssetAllowHosts, p.AssetDenyHosts...)); err != nil {
+		errs = append(errs, err)
+	}
+	if p.AssetStoreMode != "" && p.AssetStoreMode != assetStoreModeLink && p.AssetStoreMode != assetStoreModeRef {
+		errs = append(errs, fmt.Errorf("asset_store_mode must be %q or %q", assetStoreModeLink, assetStoreModeRef))
+	}
+	for _, d := range []struct{ name, value string }{
+		{"filters.created_since", p.Filters.CreatedSince},
+		{"filters.created_until", p.Filters.CreatedUntil},
+		{"filters.updated_since", p.Filters.UpdatedSince},
+		{"filters.updated_until", p.Filters.UpdatedUntil},
+	} {
+		if _, err := time.Parse(time.DateOnly, d.value); d.value != "" && err != nil {
+			errs = append(errs, fmt.Errorf("%s must be YYYY-MM-DD: %q", d.name, d.value))
+		}
+	}
+	frontMatter := p.FrontMatter
+	if frontMatter == "" {
+		frontMatter = frontMatterYAML
+	}
+	if _, err := newSiteLayout(p.Format, frontMatter, ""); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
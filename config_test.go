@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/models"
+)
+
+func TestValidateProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.Profile
+		wantErr string
+	}{
+		{"最小の設定", models.Profile{Domain: "example.qiita.com"}, ""},
+		{"すべての項目", models.Profile{
+			Domain:          "example.qiita.com",
+			TokenCommand:    "echo token",
+			BaseURL:         "http://localhost:8080",
+			AssetAllowHosts: []string{"cdn.example.com"},
+			AssetStoreMode:  assetStoreModeRef,
+			Format:          siteFormatHugo,
+			FrontMatter:     frontMatterTOML,
+			Filters:         models.ProfileFilter{CreatedSince: "2024-01-01", UpdatedUntil: "2024-12-31"},
+		}, ""},
+		{"domainなし", models.Profile{}, "domain is required"},
+		{"domainにURL", models.Profile{Domain: "https://example.qiita.com/"}, "must be a host name"},
+		{"tokenとtoken_command", models.Profile{Domain: "example.qiita.com", Token: "t", TokenCommand: "echo t"}, "mutually exclusive"},
+		{"http(s)でないbase_url", models.Profile{Domain: "example.qiita.com", BaseURL: "ftp://example.com"}, "base_url"},
+		{"ホストのないbase_url", models.Profile{Domain: "example.qiita.com", BaseURL: "http://"}, "base_url"},
+		{"不正なasset_store_mode", models.Profile{Domain: "example.qiita.com", AssetStoreMode: "copy"}, "asset_store_mode"},
+		{"不正な日付", models.Profile{Domain: "example.qiita.com", Filters: models.ProfileFilter{CreatedUntil: "2024/01/01"}}, "filters.created_until"},
+		{"未対応のformat", models.Profile{Domain: "example.qiita.com", Format: "gatsby"}, "format must be"},
+		{"JekyllとTOML", models.Profile{Domain: "example.qiita.com", Format: siteFormatJekyll, FrontMatter: frontMatterTOML}, "front matter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProfile(&tt.profile)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateProfile() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateProfile() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 複数の誤りはまとめて報告する
+func TestValidateProfileJoinsErrors(t *testing.T) {
+	err := validateProfile(&models.Profile{Token: "t", TokenCommand: "echo t", AssetStoreMode: "copy"})
+	for _, want := range []string{"domain is required", "mutually exclusive", "asset_store_mode"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateProfile() = %v, want error containing %q", err, want)
+		}
+	}
+}
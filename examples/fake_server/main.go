@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+// フィクスチャを配信するフェイクのQiita Team APIを起動する
+// 別のターミナルから `go run . -base_url <表示されたURL>` を実行するとオフラインでエクスポートを試せる
+func main() {
+	fixturesPath := flag.String("fixtures", "examples/fake_server/fixtures.json", "フィクスチャのJSONファイル")
+	token := flag.String("token", "", "指定した場合、このアクセストークン以外のリクエストを拒否する")
+	rateLimit := flag.Int("rate_limit", 0, "指定した場合、Rate-Limit系のヘッダーを返す")
+	rateRemaining := flag.Int("rate_remaining", 0, "Rate-Remainingの初期値, 未指定の場合はrate_limitと同じ")
+	rateReset := flag.Duration("rate_reset", time.Hour, "Rate-Resetまでの時間")
+	faultPath := flag.String("fault_path", "", "指定した場合、このパスに前方一致するリクエストにエラーを返す")
+	faultStatus := flag.Int("fault_status", 500, "fault_pathに返すステータスコード")
+	faultTimes := flag.Int("fault_times", 0, "fault_pathにエラーを返す回数, 0の場合は常に返す")
+	flag.Parse()
+
+	fixtures, err := qiitafake.LoadFixtures(*fixturesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var opts []qiitafake.ServerOption
+	if *token != "" {
+		opts = append(opts, qiitafake.WithToken(*token))
+	}
+
+	server := qiitafake.NewServer(fixtures, opts...)
+	defer server.Close()
+
+	if *rateLimit > 0 {
+		remaining := *rateRemaining
+		if remaining <= 0 {
+			remaining = *rateLimit
+		}
+		server.SetRateLimit(*rateLimit, remaining, time.Now().Add(*rateReset))
+	}
+
+	if *faultPath != "" {
+		server.InjectFault(qiitafake.Fault{Path: *faultPath, Status: *faultStatus, Times: *faultTimes})
+	}
+
+	fmt.Println("base url:", server.BaseURL())
+
+	// Ctrl-Cで終了する
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/qiita_export/internal/workerpool"
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+const (
+	userAgent = "qiita_export"
+)
+
+// export, syncコマンド
+// syncコマンドは -sync を指定したexportと同じ
+func runExport(args []string, syncDefault bool) error {
+	fs := newFlagSet("export")
+	outputDir := addDirFlag(fs)
+	page := fs.Int("page", 1, "default value is 1")
+	perPage := fs.Int("per_page", 100, "default value is 100")
+	query := fs.String("query", "", "Qiitaの検索クエリ (例: title:障害), 絞り込みのフラグの条件と組み合わせる")
+	filterFlags := addFilterFlags(fs)
+	baseURL := fs.String("base_url", "", "APIのベースURL (例: http://localhost:8080/api/v2), 未指定の場合は https://<DOMAIN>/api/v2")
+	concurrency := fs.Int("concurrency", 4, "記事, コメント, アセットを並行して取得する数")
+	minInterval := fs.Duration("min_interval", 0, "リクエスト間の最小の間隔 (例: 100ms)")
+	rateReserve := fs.Int("rate_reserve", 5, "残りリクエスト数がこの値以下になったら、リセットまで待機する")
+	resume := fs.Bool("resume", false, "出力ディレクトリのジャーナルを元に、前回中断したエクスポートを再開する")
+	syncMode := fs.Bool("sync", syncDefault, "updated_atを比較し、変更のあった記事のみ保存する")
+	prune := fs.Bool("prune", false, "syncの際、APIから取得できなくなった記事をローカルから削除する")
+	rewriteRenderedBody := fs.Bool("rewrite_rendered_body", false, "メタデータのrendered_bodyのアセットのURLもローカルの相対パスに置換する")
+	assetStoreDir := fs.String("asset_store", "", "指定した場合、アセットを内容のSHA-256で重複排除して共有のディレクトリに保存する (例: output/_assets)")
+	assetStoreMode := fs.String("asset_store_mode", assetStoreModeLink, "アセットストアの参照方法 (link: 記事のディレクトリにハードリンク, ref: ストアのファイルを直接参照)")
+	siteFormat := fs.String("format", "", "記事を静的サイトのレイアウトでも保存する (hugo: content/posts/<id>/index.md, jekyll: _posts/YYYY-MM-DD-<id>.md)")
+	frontMatter := fs.String("front_matter", frontMatterYAML, "formatを指定した場合のフロントマターの形式 (yaml, toml)")
+	siteDir := fs.String("site_dir", "", "formatを指定した場合の保存先, 未指定の場合は <dir>/_site")
+	timeout := fs.Duration("timeout", 0, "エクスポート全体のタイムアウト (例: 4h), 0の場合は無制限")
+	retryAttempts := fs.Int("retry", repository.DefaultRetryPolicy.MaxAttempts, "リクエストが失敗した場合の最大試行回数 (最初の1回を含む)")
+	requestTimeout := fs.Duration("request_timeout", time.Minute, "リクエスト1件あたりのタイムアウト, 0の場合は無制限")
+	team := fs.Bool("team", false, "グループ, メンバー, タグ, テンプレート, プロジェクトなどチーム単位のリソースも <dir>/_team に保存する")
+	dryRun := fs.Bool("dry-run", false, "記事の一覧のみ取得し、対象の記事数, リクエスト数, 所要時間の見積もり, 作成・上書き・削除するファイルをログとレポートの plan に出力する (記事は保存しない)")
+	planPath := fs.String("plan", "", "dry-runの計画をJSONで保存するファイル")
+	reportPath := fs.String("report", "", "実行結果のレポート (標準出力に出力するJSON) を保存するファイル")
+	profile, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// 時間計測用
+	start := time.Now()
+
+	config, err := loadConfig(profile)
+	if err != nil {
+		return err
+	}
+
+	retryPolicy := repository.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = *retryAttempts
+
+	api := repository.NewQiitaAPI(config.Domain, config.AccessToken,
+		repository.WithBaseURL(*baseURL),
+		repository.WithUserAgent(userAgent),
+		repository.WithRateLimitReserve(*rateReserve),
+		repository.WithConcurrency(*concurrency),
+		repository.WithMinInterval(*minInterval),
+		repository.WithRequestTimeout(*requestTimeout),
+		repository.WithRetryPolicy(retryPolicy),
+		repository.WithAssetHosts(config.AssetAllowHosts, config.AssetDenyHosts),
+		repository.WithLogger(slog.Default()),
+	)
+
+	// Ctrl-C (SIGINT), SIGTERMで処理中の記事を中断する
+	// 2回目のシグナルではデフォルトの動作 (即時終了) に戻す
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if *assetStoreMode != assetStoreModeLink && *assetStoreMode != assetStoreModeRef {
+		return fmt.Errorf("asset_store_mode must be %q or %q", assetStoreModeLink, assetStoreModeRef)
+	}
+	if *siteDir == "" {
+		*siteDir = filepath.Join(*outputDir, siteDirName)
+	}
+
+	filter, err := filterFlags.build()
+	if err != nil {
+		return err
+	}
+	searchQuery := filter.query(*query)
+	if searchQuery != "" {
+		slog.Info("検索クエリ", "query", searchQuery)
+	}
+
+	// 処理
+	opts := exportOptions{
+		outputDir:   *outputDir,
+		page:        *page,
+		perPage:     *perPage,
+		query:       searchQuery,
+		filter:      filter,
+		resume:      *resume,
+		sync:        *syncMode,
+		prune:       *prune,
+		concurrency: *concurrency,
+
+		rewriteRenderedBody: *rewriteRenderedBody,
+		assetStoreDir:       *assetStoreDir,
+		assetStoreMode:      *assetStoreMode,
+		siteFormat:          *siteFormat,
+		frontMatter:         *frontMatter,
+		siteDir:             *siteDir,
+
+		dryRun:   *dryRun,
+		planPath: *planPath,
+		stats:    &exportStats{},
+	}
+	err = execute(ctx, api, opts)
+	if err == nil && *team {
+		if *dryRun {
+			slog.Info("dry-runではチーム単位のリソースは取得しません")
+		} else {
+			err = exportTeam(ctx, api, *outputDir)
+		}
+	}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		err = fmt.Errorf("中断しました。-resume で再開できます: %w", context.Cause(ctx))
+	case repository.IsUnauthorized(err):
+		err = fmt.Errorf("アクセストークンが不正か、期限切れです: %w", err)
+	}
+
+	// エラーで終了した場合もレポートを出力する
+	command := "export"
+	if syncDefault {
+		command = "sync"
+	}
+	report := opts.stats.report(ctx, command, *dryRun, start, api, err)
+	if rerr := report.write(*reportPath); rerr != nil {
+		return errors.Join(err, rerr)
+	}
+	return err
+}
+
+// エクスポートの設定
+type exportOptions struct {
+	outputDir string
+	page      int
+	perPage   int
+	query     string         // 絞り込みのフラグの条件を含む検索クエリ
+	filter    *articleFilter // 検索クエリで絞り込めない条件をクライアント側で絞り込む
+	resume    bool           // ジャーナルを元に完了済みの処理をスキップする
+	sync      bool           // ローカルのコピーから変更のあった記事のみ保存する
+	prune     bool           // syncの際、削除された記事をローカルから削除する
+	// 並行して処理する記事の数
+	concurrency int
+	// rendered_bodyのアセットのURLもローカルの相対パスに置換する
+	rewriteRenderedBody bool
+	// 指定した場合、アセットを内容のハッシュで共有のストアに保存する
+	// assetStoreはexecuteで開く (dry-runの場合はplanExportでインデックスのみ読み込む)
+	assetStoreDir  string
+	assetStoreMode string
+	assetStore     *repository.AssetStore
+	// 指定した場合、記事を静的サイトのレイアウトでも保存する, siteはexecuteで作成する
+	siteFormat, frontMatter, siteDir string
+	site                             *siteLayout
+	// 一覧のみ取得して計画を表示し、planPathを指定した場合はJSONで保存する
+	dryRun   bool
+	planPath string
+	// 記事, アセットの件数の集計, 実行後にレポートとして出力する
+	stats *exportStats
+}
+
+// アセットストアの記事からの参照方法
+const (
+	assetStoreModeLink = "link" // 記事のディレクトリにハードリンクを作成する
+	assetStoreModeRef  = "ref"  // Markdownからストアのファイルを直接参照する
+)
+
+func execute(ctx context.Context, api *repository.QiitaAPI, opts exportOptions) error {
+	outputDir, page, perPage, query := opts.outputDir, opts.page, opts.perPage, opts.query
+
+	// dry-runの場合は一覧のみ取得し、記事のファイルは書き込まない
+	if opts.dryRun {
+		return planExport(ctx, api, opts)
+	}
+
+	site, err := newSiteLayout(opts.siteFormat, opts.frontMatter, opts.siteDir)
+	if err != nil {
+		return err
+	}
+	opts.site = site
+	if opts.assetStoreDir != "" {
+		if opts.assetStore, err = repository.OpenAssetStore(opts.assetStoreDir); err != nil {
+			return fmt.Errorf("failed to open asset store: %w", err)
+		}
+	}
+
+	// 進捗を記録するジャーナル
+	journal, err := repository.OpenJournal(outputDir, opts.resume)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	// 完了済みのページを飛ばす
+	if opts.resume {
+		for journal.Done(repository.JournalPage, articlePageParams(page, perPage, query)) {
+			page++
+		}
+		slog.Info("再開します", "page", page,
+			"failed_articles", len(journal.Failed(repository.JournalArticle)), "failed_assets", len(journal.Failed(repository.JournalAsset)))
+	}
+
+	// ダウンロードに失敗したアセットは一覧にして保存する
+	assets := &assetReport{}
+	defer func() {
+		if err := assets.save(outputDir); err != nil {
+			slog.Error("アセットの一覧の保存に失敗しました", "error", err)
+		}
+	}()
+
+	// アセットストアのインデックスを保存する
+	if opts.assetStore != nil {
+		defer func() {
+			if err := opts.assetStore.Save(); err != nil {
+				slog.Error("アセットストアのインデックスの保存に失敗しました", "error", err)
+			}
+		}()
+	}
+
+	// 記事とアセットの一覧を保存する (中断した場合も、その時点の出力ディレクトリの内容で保存する)
+	manifest := newManifestBuilder(query)
+	defer func() {
+		if _, err := os.Stat(outputDir); err != nil {
+			return
+		}
+		if err := manifest.save(outputDir, api, opts.assetStore); err != nil {
+			slog.Error("マニフェストの保存に失敗しました", "error", err)
+		}
+	}()
+
+	// 同期の場合は保存済みの記事を読み込んでおく
+	var locals map[string]localArticle
+	report := newSyncReport()
+	if opts.sync {
+		opts.stats.sync = report
+		locals, err = loadLocalArticles(outputDir)
+		if err != nil {
+			return fmt.Errorf("保存済みの記事の読み込みに失敗しました: %w", err)
+		}
+	}
+
+	pager, err := api.ArticlePages(page, perPage, query)
+	if err != nil {
+		return err
+	}
+	for !pager.Done() {
+		// リトライはQiitaAPIのリトライの設定に従って行われる
+		result, err := pager.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to request page=%d, per_page=%d: %w", pager.Page(), perPage, err)
+		}
+		page, total, articles := result.Number, result.Total, result.Items
+		params := articlePageParams(page, perPage, query)
+		manifest.setTotalCount(total)
+
+		// outputディレクトリの作成
+		if err := os.MkdirAll(outputDir, 0777); err != nil {
+			return err
+		}
+
+		// 記事毎の処理を並行して実行する
+		// アセットの失敗で失敗として記録した記事がある場合は、再開時にページごと取得し直せるよう、ページを完了にしない
+		var pageFailed atomic.Bool
+		err = workerpool.Run(len(articles), opts.concurrency, func(i int) error {
+			v := &articles[i]
+			if !opts.filter.match(v) {
+				return nil
+			}
+			if journal.Done(repository.JournalArticle, v.ID) {
+				slog.Info("完了済みのためスキップします", "id", v.ID, "title", v.Title)
+				opts.stats.articlesSkipped.Add(1)
+				return nil
+			}
+			if opts.sync && !report.needsExport(v, locals) {
+				opts.stats.articlesSkipped.Add(1)
+				return nil
+			}
+
+			manifest.markExported(v.ID)
+			failedAssets, err := exportArticle(ctx, api, journal, v, opts, assets)
+			if err != nil {
+				// 一覧の取得後に削除された記事はスキップする
+				if repository.IsNotFound(err) {
+					slog.Warn("削除された記事のためスキップします", "id", v.ID, "title", v.Title, "error", err)
+					opts.stats.articlesSkipped.Add(1)
+					return journal.MarkDone(repository.JournalArticle, v.ID)
+				}
+				if ctx.Err() == nil {
+					opts.stats.articlesFailed.Add(1)
+				}
+				if jerr := journal.MarkFailed(repository.JournalArticle, v.ID, err); jerr != nil {
+					return errors.Join(err, jerr)
+				}
+				return err
+			}
+
+			if local, ok := locals[v.ID]; ok {
+				if err := removeStaleFiles(local, articleDir(outputDir, v), sanitizeFilename(v.Title)); err != nil {
+					opts.stats.articlesFailed.Add(1)
+					return fmt.Errorf("古いファイルの削除に失敗しました: %w", err)
+				}
+			}
+
+			// アセットの失敗ではエクスポート全体を止めず、再開時に再試行できるよう失敗として記録する
+			if failedAssets > 0 {
+				pageFailed.Store(true)
+				opts.stats.articlesFailed.Add(1)
+				return journal.MarkFailed(repository.JournalArticle, v.ID, fmt.Errorf("%d assets failed", failedAssets))
+			}
+			opts.stats.articlesOK.Add(1)
+			return journal.MarkDone(repository.JournalArticle, v.ID)
+		})
+		if err != nil {
+			return err
+		}
+
+		if opts.assetStore != nil {
+			if err := opts.assetStore.Save(); err != nil {
+				return err
+			}
+		}
+		if !pageFailed.Load() {
+			if err := journal.MarkDone(repository.JournalPage, params); err != nil {
+				return err
+			}
+		}
+
+		if pager.Done() {
+			break
+		}
+
+		// 進捗状況
+		completed := min(page*perPage, total)
+		progress := float64(completed) * 100 / float64(total)
+		remainingPages := (max(0, total-page*perPage) + perPage - 1) / perPage
+
+		slog.Info("進捗", "progress", fmt.Sprintf("%.1f%%", progress), "page", page, "remaining_pages", remainingPages)
+		if rl, ok := api.RateLimit(); ok {
+			slog.Info("レート制限", "remaining", rl.Remaining, "limit", rl.Limit, "reset", rl.Reset.Format(time.DateTime))
+		}
+	}
+
+	if opts.sync {
+		// 一部の記事のみを取得した場合は、削除されたかどうか判定できない
+		if query != "" || opts.filter.active() || opts.page != 1 || opts.resume {
+			slog.Info("query, 絞り込みの条件, page, resumeを指定した場合は削除された記事を検出しません")
+		} else if err := report.detectDeleted(locals, opts.prune, opts.site); err != nil {
+			return fmt.Errorf("削除された記事の処理に失敗しました: %w", err)
+		}
+		report.print()
+	}
+
+	return nil
+}
+
+// 記事一覧のリクエストのクエリ文字列
+// ジャーナルのページのキーにも使うため、同じ条件では同じ文字列になる
+func articlePageParams(page, perPage int, query string) string {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("per_page", strconv.Itoa(perPage))
+	if query != "" {
+		params.Set("query", query)
+	}
+	return params.Encode()
+}
+
+// 記事1件分のコメント, 絵文字リアクション, メタデータ, Markdown, アセットを保存する
+// ジャーナルで完了済みの処理はスキップする
+// 中断された場合や記事が削除されていた場合、この実行で作成した記事のディレクトリは削除して、再開時に最初からやり直す
+// ダウンロードに失敗したアセットは元のURLのまま残してassetsに記録し、その件数を返す
+func exportArticle(ctx context.Context, api *repository.QiitaAPI, journal *repository.Journal, v *models.Article, opts exportOptions, assets *assetReport) (failedAssets int, retErr error) {
+	// mkdir
+	artDir := articleDir(opts.outputDir, v)
+	_, statErr := os.Stat(artDir)
+	if err := os.MkdirAll(artDir, 0777); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if retErr == nil || statErr == nil {
+			return
+		}
+		if ctx.Err() == nil && !repository.IsNotFound(retErr) {
+			return
+		}
+		slog.Warn("書きかけの記事を削除します", "dir", artDir)
+		retErr = errors.Join(retErr,
+			os.RemoveAll(artDir),
+			journal.MarkFailed(repository.JournalComments, v.ID, retErr),
+		)
+	}()
+
+	if journal.Done(repository.JournalComments, v.ID) {
+		// 保存済みのメタデータからコメント, 絵文字リアクションを復元する
+		repo := repository.ArticleMetadata{}
+		saved, err := repo.GetArticle(filepath.Join(artDir, sanitizeFilename(v.Title)+"_metadata.json"))
+		if err != nil {
+			return 0, fmt.Errorf("保存済みのメタデータの読み込みに失敗しました: %w", err)
+		}
+		v.Comments = saved.Comments
+		v.EmojiReactions = saved.EmojiReactions
+	} else {
+		// コメント, 絵文字を並行して取得する
+		err := workerpool.Run(2, api.Concurrency(), func(i int) error {
+			var err error
+			switch i {
+			case 0:
+				if v.Comments, err = api.RequestCommentsContext(ctx, v.ID); err != nil {
+					return fmt.Errorf("コメントの取得に失敗しました: %w", err)
+				}
+			case 1:
+				if v.EmojiReactions, err = api.RequestArticleReactionsContext(ctx, v.ID); err != nil {
+					return fmt.Errorf("絵文字リアクションの取得に失敗しました: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if err := downloadArticleToLocal(v, artDir); err != nil {
+			return 0, fmt.Errorf("記事のダウンロードに失敗しました: %w", err)
+		}
+		if err := journal.MarkDone(repository.JournalComments, v.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	// アセットを並行してダウンロードする
+	assetURLs := api.ExtractAssetURLs(v.Body, v.RenderedBody)
+	names := repository.AssetFileNames(assetURLs)
+	results := make([]repository.AssetResult, len(assetURLs))
+	err := workerpool.Run(len(assetURLs), api.Concurrency(), func(i int) error {
+		s := assetURLs[i]
+		key := repository.AssetJournalKey(v.ID, s)
+		results[i] = repository.AssetResult{URL: s, LocalPath: filepath.Join(artDir, names[s])}
+		// アセットストアを使う場合は、ダウンロード済みかどうかをストアのインデックスで判定する
+		if opts.assetStore == nil && journal.Done(repository.JournalAsset, key) {
+			opts.stats.assetsSkipped.Add(1)
+			return nil
+		}
+
+		localPath, err := downloadAsset(ctx, api, s, results[i].LocalPath, opts)
+		if err != nil {
+			// 中断された場合は記事ごとやり直す
+			if ctx.Err() != nil {
+				return err
+			}
+			results[i].Err = err
+			opts.stats.addAsset(err)
+			slog.Warn("アセットのダウンロードに失敗しました", "id", v.ID, "url", s, "status", repository.StatusCode(err), "error", err)
+			assets.add(assetFailure{ArticleID: v.ID, Title: v.Title, Dir: artDir, URL: s, Error: err.Error()})
+			return journal.MarkFailed(repository.JournalAsset, key, err)
+		}
+		results[i].LocalPath = localPath
+		opts.stats.addAsset(nil)
+		return journal.MarkDone(repository.JournalAsset, key)
+	})
+	if err != nil {
+		return 0, err
+	}
+	slog.Debug("アセットを取得しました", "id", v.ID, "total", len(assetURLs))
+
+	// ダウンロードしたアセットのURLをローカルの相対パスに置換する
+	for _, r := range results {
+		if r.Err != nil {
+			failedAssets++
+		}
+	}
+	if len(assetURLs) > 0 {
+		if err := rewriteArticleAssets(v, artDir, results, opts.rewriteRenderedBody); err != nil {
+			return failedAssets, fmt.Errorf("アセットのパスの置換に失敗しました: %w", err)
+		}
+	}
+	if opts.site != nil {
+		if err := opts.site.write(v, results); err != nil {
+			return failedAssets, fmt.Errorf("静的サイト用の記事の保存に失敗しました: %w", err)
+		}
+	}
+
+	return failedAssets, nil
+}
+
+// アセットを1件ダウンロードし、Markdownから参照するファイルのパスを返す
+// アセットストアを使う場合はストアにダウンロードし、linkモードでは記事のディレクトリにハードリンクを作成、
+// refモードではストアのファイルを直接参照する
+func downloadAsset(ctx context.Context, api *repository.QiitaAPI, assetURL, localPath string, opts exportOptions) (string, error) {
+	if opts.assetStore == nil {
+		return localPath, api.DownloadAssetContext(ctx, assetURL, localPath)
+	}
+
+	e, err := api.DownloadAssetToStoreContext(ctx, opts.assetStore, assetURL)
+	if err != nil {
+		return "", err
+	}
+	storePath := opts.assetStore.FilePath(e)
+	if opts.assetStoreMode == assetStoreModeRef {
+		return storePath, nil
+	}
+
+	if err := linkFile(storePath, localPath); err != nil {
+		return "", fmt.Errorf("failed to link asset: %w", err)
+	}
+	return localPath, nil
+}
+
+// ハードリンクを作成する, ファイルシステムをまたぐなどで作成できない場合はコピーする
+func linkFile(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, b, 0666)
+}
+
+// Markdown (とrendered_body) のアセットのURLを、ダウンロードしたファイルへの相対パスに置換して保存し直す
+// メタデータのbodyはAPIから取得した内容のまま残す
+func rewriteArticleAssets(v *models.Article, artDir string, results []repository.AssetResult, rewriteRenderedBody bool) error {
+	sanitizedTitle := sanitizeFilename(v.Title)
+
+	body := repository.RewriteAssetURLs(v.Body, artDir, results)
+	if err := writeFileAtomic(filepath.Join(artDir, sanitizedTitle+".md"), []byte(body), 0666); err != nil {
+		return fmt.Errorf("failed to write markdown: %w", err)
+	}
+
+	if rewriteRenderedBody {
+		art := *v
+		art.RenderedBody = repository.RewriteRenderedAssetURLs(v.RenderedBody, artDir, results)
+		metadataJSON, err := json.MarshalIndent(&art, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		if err := writeFileAtomic(filepath.Join(artDir, sanitizedTitle+"_metadata.json"), metadataJSON, 0666); err != nil {
+			return fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// グループに属さない記事 (qiita.comの記事) を保存するディレクトリ名
+// グループのURL名として使えない _ から始め、実在するグループと重ならないようにする
+const noGroupDirName = "_public"
+
+// 記事を保存するディレクトリ
+func articleDir(outputDir string, v *models.Article) string {
+	groupDir := filepath.Join(outputDir, groupDirName(v))
+	return filepath.Join(groupDir, v.ID) // 記事名にSlashがある場合にエラーになるため、IDを採用
+}
+
+// 記事のグループのディレクトリ名
+func groupDirName(v *models.Article) string {
+	if v.Group == nil {
+		return noGroupDirName
+	}
+	return v.Group.Name
+}
+
+func downloadArticleToLocal(art *models.Article, artDir string) error {
+	// ファイル名のサニタイズ
+	sanitizedTitle := sanitizeFilename(art.Title)
+
+	// メタデータの保存
+	metadataPath := filepath.Join(artDir, sanitizedTitle+"_metadata.json")
+	metadataJSON, err := json.MarshalIndent(art, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	if err := writeFileAtomic(metadataPath, metadataJSON, 0666); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	slog.Debug("メタデータを保存しました", "path", metadataPath)
+
+	// Markdownファイルの保存
+	mdPath := filepath.Join(artDir, sanitizedTitle+".md")
+	if err := writeFileAtomic(mdPath, []byte(art.Body), 0666); err != nil {
+		return fmt.Errorf("failed to write markdown: %w", err)
+	}
+	slog.Info("記事を保存しました", "id", art.ID, "title", art.Title, "dir", artDir)
+
+	return nil
+}
+
+// 一時ファイルに書き込んでからリネームし、書きかけのファイルが残らないようにする
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// ファイル名として使用できない文字をサニタイズする関数
+func sanitizeFilename(filename string) string {
+	// Windowsでも使用できるよう、一般的な禁止文字をすべて置換
+	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
+	result := filename
+	for _, char := range invalidChars {
+		result = strings.ReplaceAll(result, char, "_")
+	}
+	return result
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/qiitafake"
+	"github.com/qiita_export/repository"
+)
+
+// テスト用の記事のID
+const (
+	testID1 = "0000000000000000000a"
+	testID2 = "0000000000000000000b"
+	testID3 = "0000000000000000000c"
+)
+
+func TestMain(m *testing.M) {
+	// 進捗のログはテストの出力に含めない
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func testArticle(id, title, group string, updated time.Time) models.Article {
+	v := models.Article{
+		ID:        id,
+		Title:     title,
+		Body:      "# " + title,
+		CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+		UpdatedAt: updated,
+		User:      &models.User{ID: "alice"},
+	}
+	if group != "" {
+		v.Group = &models.Group{Name: group, URLName: group}
+	}
+	return v
+}
+
+// 3件の記事のフィクスチャ, 1件目はコメントとアセットを含む
+func testFixtures() qiitafake.Fixtures {
+	updated := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	a := testArticle(testID1, "アセットのある記事", "dev", updated)
+	a.Body += "\n![img](" + qiitafake.ServerURLPlaceholder + "/files/a.png)\n```\n" + qiitafake.ServerURLPlaceholder + "/files/a.png\n```\n"
+	a.CommentsCount = 1
+	return qiitafake.Fixtures{
+		Articles: []models.Article{
+			a,
+			testArticle(testID2, "記事2", "dev", updated),
+			testArticle(testID3, "グループなし", "", updated),
+		},
+		Comments: map[string][]models.Comment{
+			testID1: {{ID: "c1", Body: "コメント", User: models.User{ID: "bob"}}},
+		},
+		Assets: map[string]qiitafake.Asset{"/files/a.png": {ContentType: "image/png", Body: testPNG}},
+	}
+}
+
+// フェイクサーバーに向けたQiitaAPI, リトライは3回まで待機せずに行う
+func newTestAPI(t *testing.T, server *qiitafake.Server) *repository.QiitaAPI {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := repository.DefaultRetryPolicy
+	policy.MaxAttempts, policy.BaseDelay, policy.MaxDelay = 3, time.Millisecond, time.Millisecond
+	return repository.NewQiitaAPI(u.Host, "token",
+		repository.WithBaseURL(server.BaseURL()),
+		repository.WithMinInterval(0),
+		repository.WithConcurrency(2),
+		repository.WithRetryPolicy(policy),
+	)
+}
+
+// 1ページ2件でエクスポートする設定
+func testOptions(dir string) exportOptions {
+	return exportOptions{
+		outputDir:   dir,
+		page:        1,
+		perPage:     2,
+		filter:      &articleFilter{},
+		concurrency: 2,
+		stats:       &exportStats{},
+	}
+}
+
+// 記事のMarkdownを読み込む
+func readMarkdown(t *testing.T, dir string, v models.Article) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(articleDir(dir, &v), sanitizeFilename(v.Title)+".md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	fixtures := testFixtures()
+	server := qiitafake.NewServer(fixtures)
+	defer server.Close()
+	dir := t.TempDir()
+
+	opts := testOptions(dir)
+	if err := execute(context.Background(), newTestAPI(t, server), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := opts.stats.articlesOK.Load(); got != 3 {
+		t.Errorf("articles ok = %d, want 3", got)
+	}
+
+	for _, v := range fixtures.Articles {
+		readMarkdown(t, dir, v)
+	}
+	// アセットは相対パスに置換し、コードブロック内のURLは残す
+	if md := readMarkdown(t, dir, fixtures.Articles[0]); !strings.Contains(md, "![img](a.png)") || !strings.Contains(md, server.URL+"/files/a.png\n```") {
+		t.Errorf("markdown = %q", md)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "dev", testID1, "a.png")); err != nil || string(b) != string(testPNG) {
+		t.Errorf("asset = %q, %v", b, err)
+	}
+	// グループのない記事は _public/ に保存する
+	if _, err := os.Stat(filepath.Join(dir, noGroupDirName, testID3)); err != nil {
+		t.Error(err)
+	}
+
+	// 一覧は Total-Count (3件) に達したページで止める
+	var listing []string
+	for _, r := range server.Requests() {
+		if strings.HasPrefix(r, "/api/v2/items?") {
+			listing = append(listing, r)
+		}
+	}
+	if len(listing) != 2 {
+		t.Errorf("listing requests = %v, want 2 pages", listing)
+	}
+}
+
+// ダウンロードに成功したアセットのみ相対パスに置換し、失敗したアセットは元のURLのまま asset_report.json に記録する
+func TestExecuteArticleAssets(t *testing.T) {
+	fixtures := testFixtures()
+	a := &fixtures.Articles[0]
+	missing := qiitafake.ServerURLPlaceholder + "/files/missing.png"
+	a.Body += "![missing](" + missing + ")\n"
+	a.RenderedBody = `<p><img src="` + qiitafake.ServerURLPlaceholder + `/files/a.png"><img src="` + missing + `"></p>`
+	server := qiitafake.NewServer(fixtures)
+	defer server.Close()
+	dir := t.TempDir()
+
+	opts := testOptions(dir)
+	opts.rewriteRenderedBody = true
+	if err := execute(context.Background(), newTestAPI(t, server), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := opts.stats.articlesFailed.Load(); got != 1 {
+		t.Errorf("articles failed = %d, want 1", got)
+	}
+
+	missingURL := server.URL + "/files/missing.png"
+	md := readMarkdown(t, dir, *a)
+	if !strings.Contains(md, "![img](a.png)") || !strings.Contains(md, "![missing]("+missingURL+")") {
+		t.Errorf("markdown = %q", md)
+	}
+
+	b, err := os.ReadFile(filepath.Join(articleDir(dir, a), sanitizeFilename(a.Title)+"_metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata models.Article
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p><img src="a.png"><img src="` + missingURL + `"></p>`; metadata.RenderedBody != want {
+		t.Errorf("rendered_body = %q, want %q", metadata.RenderedBody, want)
+	}
+
+	b, err = os.ReadFile(filepath.Join(dir, assetReportFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []assetFailure
+	if err := json.Unmarshal(b, &failures); err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].ArticleID != testID1 || failures[0].URL != missingURL {
+		t.Errorf("asset report = %+v", failures)
+	}
+}
+
+// アセットストアを使う場合、linkモードは記事のディレクトリにハードリンクを作成し、refモードはストアのファイルを参照する
+func TestExecuteAssetStore(t *testing.T) {
+	tests := []struct {
+		mode     string
+		wantLink bool
+	}{
+		{assetStoreModeLink, true},
+		{assetStoreModeRef, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			fixtures := testFixtures()
+			server := qiitafake.NewServer(fixtures)
+			defer server.Close()
+			dir := t.TempDir()
+
+			opts := testOptions(dir)
+			opts.assetStoreDir = filepath.Join(dir, "_assets")
+			opts.assetStoreMode = tt.mode
+			if err := execute(context.Background(), newTestAPI(t, server), opts); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := repository.OpenAssetStore(opts.assetStoreDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, ok := store.Lookup(server.URL + "/files/a.png")
+			if !ok {
+				t.Fatal("asset is not in the store index")
+			}
+			stored, err := os.Stat(store.FilePath(e))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := &fixtures.Articles[0]
+			artDir := articleDir(dir, a)
+			linked, err := os.Stat(filepath.Join(artDir, "a.png"))
+			if tt.wantLink {
+				if err != nil || !os.SameFile(stored, linked) {
+					t.Errorf("a.png is not a hard link to the store: %v", err)
+				}
+			} else if !os.IsNotExist(err) {
+				t.Errorf("a.png is created in ref mode: %v", err)
+			}
+
+			want := "![img](a.png)"
+			if !tt.wantLink {
+				rel, _ := filepath.Rel(artDir, store.FilePath(e))
+				want = "![img](" + filepath.ToSlash(rel) + ")"
+			}
+			if md := readMarkdown(t, dir, *a); !strings.Contains(md, want) {
+				t.Errorf("markdown = %q, want %q", md, want)
+			}
+		})
+	}
+}
+
+// 失敗した後に -resume で再開すると、失敗した記事のみ取得し直す
+func TestExecuteResume(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault qiitafake.Fault
+		// 1回目の実行がエラーで終了するか
+		wantErr bool
+		// 再開時に一覧以外で送信するリクエスト (失敗した記事の取得し直し)
+		wantRetried []string
+		// 再開時に一覧を取得し直すページ
+		wantPages []string
+	}{
+		{
+			name:        "アセットの失敗",
+			fault:       qiitafake.Fault{Path: "/files/a.png", Status: http.StatusInternalServerError, Times: 3},
+			wantRetried: []string{"/files/a.png"},
+			wantPages:   []string{"1", "2"},
+		},
+		{
+			name:        "2ページ目の記事の失敗",
+			fault:       qiitafake.Fault{Path: "/api/v2/items/" + testID3 + "/comments", Status: http.StatusInternalServerError, Times: 3},
+			wantErr:     true,
+			wantRetried: []string{"/api/v2/items/" + testID3 + "/comments", "/api/v2/items/" + testID3 + "/reactions"},
+			wantPages:   []string{"2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := qiitafake.NewServer(testFixtures())
+			defer server.Close()
+			server.InjectFault(tt.fault)
+			dir := t.TempDir()
+
+			err := execute(context.Background(), newTestAPI(t, server), testOptions(dir))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("1st execute() = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			before := len(server.Requests())
+			opts := testOptions(dir)
+			opts.resume = true
+			if err := execute(context.Background(), newTestAPI(t, server), opts); err != nil {
+				t.Fatalf("resume: %v", err)
+			}
+			if got := opts.stats.articlesOK.Load(); got != 1 {
+				t.Errorf("articles ok = %d, want 1", got)
+			}
+
+			var retried, pages []string
+			for _, r := range server.Requests()[before:] {
+				u, _ := url.Parse(r)
+				if u.Path == "/api/v2/items" {
+					pages = append(pages, u.Query().Get("page"))
+				} else {
+					retried = append(retried, u.Path)
+				}
+			}
+			// コメントとリアクションは並行して取得するため順序は問わない
+			slices.Sort(retried)
+			if !slices.Equal(retried, tt.wantRetried) {
+				t.Errorf("retried requests = %v, want %v", retried, tt.wantRetried)
+			}
+			if !slices.Equal(pages, tt.wantPages) {
+				t.Errorf("listed pages = %v, want %v", pages, tt.wantPages)
+			}
+
+			// 再開後は全ての記事が揃っている
+			problems, articles, err := verifyOutputDir(dir)
+			if err != nil || len(problems) > 0 || articles != 3 {
+				t.Errorf("verify: articles=%d, problems=%v, err=%v", articles, problems, err)
+			}
+			if md := readMarkdown(t, dir, testFixtures().Articles[0]); !strings.Contains(md, "![img](a.png)") {
+				t.Errorf("asset is not rewritten after resume: %q", md)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+// 記事の絞り込み条件
+// APIの検索クエリで絞り込める条件はクエリに変換し、取得した記事はクライアント側でも全ての条件で絞り込む
+// (グループ, 複数のタグやユーザー, 記事IDの一覧はAPIでは絞り込めない)
+type articleFilter struct {
+	groups       []string // グループのURL名のいずれか
+	tags         []string // タグのいずれか (大文字と小文字を区別しない)
+	users        []string // 投稿者のユーザーIDのいずれか
+	createdSince string   // YYYY-MM-DD, この日以降に作成された記事
+	createdUntil string   // YYYY-MM-DD, この日までに作成された記事
+	updatedSince string
+	updatedUntil string
+	includeIDs   map[string]bool // 指定した場合、この記事IDのみ
+	excludeIDs   map[string]bool
+}
+
+// 絞り込み条件のフラグの値
+type filterFlags struct {
+	groups, tags, users        *string
+	createdSince, createdUntil *string
+	updatedSince, updatedUntil *string
+	includeIDs, excludeIDs     *string
+}
+
+func addFilterFlags(fs *flag.FlagSet) filterFlags {
+	return filterFlags{
+		groups:       fs.String("group", "", "グループのURL名 (カンマ区切りでいずれか)"),
+		tags:         fs.String("tag", "", "タグ (カンマ区切りでいずれか)"),
+		users:        fs.String("user", "", "投稿者のユーザーID (カンマ区切りでいずれか)"),
+		createdSince: fs.String("created_since", "", "この日以降に作成された記事 (YYYY-MM-DD)"),
+		createdUntil: fs.String("created_until", "", "この日までに作成された記事 (YYYY-MM-DD)"),
+		updatedSince: fs.String("updated_since", "", "この日以降に更新された記事 (YYYY-MM-DD)"),
+		updatedUntil: fs.String("updated_until", "", "この日までに更新された記事 (YYYY-MM-DD)"),
+		includeIDs:   fs.String("include_ids", "", "エクスポートする記事IDの一覧のファイル (1行に1件)"),
+		excludeIDs:   fs.String("exclude_ids", "", "エクスポートしない記事IDの一覧のファイル (1行に1件)"),
+	}
+}
+
+func (f filterFlags) build() (*articleFilter, error) {
+	filter := &articleFilter{
+		groups:       splitComma(*f.groups),
+		tags:         splitComma(*f.tags),
+		users:        splitComma(*f.users),
+		createdSince: *f.createdSince,
+		createdUntil: *f.createdUntil,
+		updatedSince: *f.updatedSince,
+		updatedUntil: *f.updatedUntil,
+	}
+	for _, d := range []struct{ name, value string }{
+		{"created_since", filter.createdSince},
+		{"created_until", filter.createdUntil},
+		{"updated_since", filter.updatedSince},
+		{"updated_until", filter.updatedUntil},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, d.value); err != nil {
+			return nil, fmt.Errorf("%s must be YYYY-MM-DD: %q", d.name, d.value)
+		}
+	}
+
+	var err error
+	if filter.includeIDs, err = readIDList(*f.includeIDs); err != nil {
+		return nil, err
+	}
+	if filter.excludeIDs, err = readIDList(*f.excludeIDs); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// カンマ区切りの値を分割する, 空の要素は除く
+func splitComma(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// 1行に1件の記事IDのファイルを読み込む, 空行と # から始まる行は無視する
+func readIDList(path string) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID list: %w", err)
+	}
+	defer f.Close()
+
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ID list %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// 条件が指定されているかどうか
+func (f *articleFilter) active() bool {
+	return len(f.groups) > 0 || len(f.tags) > 0 || len(f.users) > 0 ||
+		f.createdSince != "" || f.createdUntil != "" || f.updatedSince != "" || f.updatedUntil != "" ||
+		f.includeIDs != nil || len(f.excludeIDs) > 0
+}
+
+// -query の値に、APIで絞り込める条件を加えた検索クエリ
+// 日付はQiitaの検索で確実に使える > と < に変換する
+// タグ, ユーザーが複数の場合 (いずれかに一致) は、検索クエリでは表現できないためクライアント側のみで絞り込む
+func (f *articleFilter) query(raw string) string {
+	var terms []string
+	if raw = strings.TrimSpace(raw); raw != "" {
+		terms = append(terms, raw)
+	}
+	if len(f.tags) == 1 {
+		terms = append(terms, "tag:"+f.tags[0])
+	}
+	if len(f.users) == 1 {
+		terms = append(terms, "user:"+f.users[0])
+	}
+	addRange := func(key, since, until string) {
+		if since != "" {
+			terms = append(terms, fmt.Sprintf("%s:>%s", key, addDays(since, -1)))
+		}
+		if until != "" {
+			terms = append(terms, fmt.Sprintf("%s:<%s", key, addDays(until, 1)))
+		}
+	}
+	addRange("created", f.createdSince, f.createdUntil)
+	addRange("updated", f.updatedSince, f.updatedUntil)
+	return strings.Join(terms, " ")
+}
+
+// YYYY-MM-DDの日付にdays日を加える, 事前に検証済みの値のみ渡す
+func addDays(date string, days int) string {
+	t, _ := time.Parse(time.DateOnly, date)
+	return t.AddDate(0, 0, days).Format(time.DateOnly)
+}
+
+// 記事が全ての条件に一致するかどうか
+// 日付は記事の日時のタイムゾーン (Qiitaは+09:00) での日付で比較する
+func (f *articleFilter) match(v *models.Article) bool {
+	if f.includeIDs != nil && !f.includeIDs[v.ID] {
+		return false
+	}
+	if f.excludeIDs[v.ID] {
+		return false
+	}
+	if len(f.groups) > 0 && (v.Group == nil || !slices.Contains(f.groups, v.Group.URLName)) {
+		return false
+	}
+	if len(f.users) > 0 && (v.User == nil || !slices.ContainsFunc(f.users, func(u string) bool { return strings.EqualFold(u, v.User.ID) })) {
+		return false
+	}
+	if len(f.tags) > 0 && !slices.ContainsFunc(v.Tags, func(t models.Tagging) bool {
+		return slices.ContainsFunc(f.tags, func(s string) bool { return strings.EqualFold(s, t.Name) })
+	}) {
+		return false
+	}
+	return inRange(v.CreatedAt, f.createdSince, f.createdUntil) && inRange(v.UpdatedAt, f.updatedSince, f.updatedUntil)
+}
+
+func inRange(t time.Time, since, until string) bool {
+	date := t.Format(time.DateOnly)
+	return (since == "" || date >= since) && (until == "" || date <= until)
+}
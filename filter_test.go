@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+func TestArticleFilterMatch(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	v := &models.Article{
+		ID: "a",
+		// UTCでは前日になる日時
+		CreatedAt: time.Date(2024, 3, 1, 0, 30, 0, 0, jst),
+		UpdatedAt: time.Date(2024, 4, 1, 12, 0, 0, 0, jst),
+		User:      &models.User{ID: "Alice"},
+		Group:     &models.Group{URLName: "dev"},
+		Tags:      []models.Tagging{{Name: "Go"}, {Name: "AWS"}},
+	}
+	tests := []struct {
+		name   string
+		filter articleFilter
+		want   bool
+	}{
+		{"条件なし", articleFilter{}, true},
+		{"グループ", articleFilter{groups: []string{"infra", "dev"}}, true},
+		{"グループ不一致", articleFilter{groups: []string{"infra"}}, false},
+		{"タグは大文字と小文字を区別しない", articleFilter{tags: []string{"go"}}, true},
+		{"タグ不一致", articleFilter{tags: []string{"Rust"}}, false},
+		{"ユーザー", articleFilter{users: []string{"alice"}}, true},
+		{"作成日の当日", articleFilter{createdSince: "2024-03-01", createdUntil: "2024-03-01"}, true},
+		{"作成日は記事のタイムゾーンで比較する", articleFilter{createdUntil: "2024-02-29"}, false},
+		{"更新日の範囲外", articleFilter{updatedSince: "2024-04-02"}, false},
+		{"IDの一覧", articleFilter{includeIDs: map[string]bool{"a": true}}, true},
+		{"IDの一覧にない", articleFilter{includeIDs: map[string]bool{}}, false},
+		{"除外するID", articleFilter{excludeIDs: map[string]bool{"a": true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.match(v); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArticleFilterQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter articleFilter
+		raw    string
+		want   string
+	}{
+		{"条件なし", articleFilter{}, "", ""},
+		{"-queryに加える", articleFilter{tags: []string{"Go"}, users: []string{"alice"}}, " title:設計 ", "title:設計 tag:Go user:alice"},
+		{"複数のタグはクエリにしない", articleFilter{tags: []string{"Go", "AWS"}}, "", ""},
+		{"日付は前後の日に変換する", articleFilter{createdSince: "2024-03-01", createdUntil: "2024-03-31"}, "", "created:>2024-02-29 created:<2024-04-01"},
+		{"年をまたぐ", articleFilter{updatedSince: "2024-01-01", updatedUntil: "2024-12-31"}, "", "updated:>2023-12-31 updated:<2025-01-01"},
+		{"グループはクエリにしない", articleFilter{groups: []string{"dev"}}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.query(tt.raw); got != tt.want {
+				t.Errorf("query(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,4 +2,10 @@ module github.com/qiita_export
 
 go 1.23.1
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.17.11
+	github.com/yuin/goldmark v1.8.6
+	gopkg.in/yaml.v3 v3.0.1
+)
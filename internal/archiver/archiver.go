@@ -0,0 +1,398 @@
+// Package archiver はエクスポートしたディレクトリを分割してアーカイブ (zip, tar.gz, tar.zst) にする
+//
+// -dir 直下のディレクトリを単位として、ディレクトリ数と合計サイズの上限ごとにアーカイブを分ける
+// 1つのディレクトリが合計サイズの上限を超える場合は、その中のディレクトリとファイルを単位として分ける
+// アーカイブごとに、含まれるファイルの一覧 (マニフェスト) とSHA-256のサイドカーを出力する
+//
+//	<out>/<name>_1.zip
+//	<out>/<name>_1.zip.manifest.json
+//	<out>/<name>_1.zip.sha256      sha256sum -c で検証できる形式
+package archiver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// アーカイブの形式
+const (
+	FormatZip    = "zip"
+	FormatTarGz  = "tar.gz"
+	FormatTarZst = "tar.zst"
+)
+
+// Formats は対応している形式
+var Formats = []string{FormatZip, FormatTarGz, FormatTarZst}
+
+// ファイル1件あたりのヘッダー (zipのローカルヘッダーとセントラルディレクトリ, tarのヘッダーとパディング) の見積もり
+const entryOverhead = 1536
+
+// Options はアーカイブの設定
+type Options struct {
+	SourceDir string   // エクスポートしたディレクトリ
+	OutputDir string   // アーカイブの出力先
+	Name      string   // アーカイブのファイル名の接頭辞, 空の場合はSourceDirのディレクトリ名
+	Format    string   // FormatZip, FormatTarGz, FormatTarZst
+	MaxDirs   int      // 1つのアーカイブに含めるディレクトリの数, 0以下の場合は無制限
+	MaxBytes  int64    // 1つのアーカイブのサイズの上限, 0以下の場合は無制限
+	Exclude   []string // パス (SourceDirからの相対パス) にいずれかを含むファイル, ディレクトリは対象外とする
+	Part      int      // 指定した場合、その番号のアーカイブのみ作成する (1から)
+}
+
+// Manifest はアーカイブ1件の内容
+type Manifest struct {
+	Archive   string         `json:"archive"` // アーカイブのファイル名
+	Format    string         `json:"format"`
+	Part      int            `json:"part"`
+	Parts     int            `json:"parts"`
+	Size      int64          `json:"size"`   // アーカイブのサイズ
+	SHA256    string         `json:"sha256"` // アーカイブのSHA-256
+	CreatedAt time.Time      `json:"created_at"`
+	Dirs      []string       `json:"dirs"` // 含まれるディレクトリ (分割の単位)
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile はアーカイブ内のファイル1件
+type ManifestFile struct {
+	Path   string `json:"path"` // アーカイブ内のパス
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// アーカイブに含めるファイル
+type file struct {
+	abs  string
+	rel  string // SourceDirからの相対パス (スラッシュ区切り)
+	name string // アーカイブ内のパス
+	info os.FileInfo
+}
+
+// 分割の単位 (ディレクトリ, または上限を超えたディレクトリ内のファイル)
+type unit struct {
+	name  string // SourceDirからの相対パス
+	files []file
+}
+
+func (u unit) cost() int64 {
+	var n int64
+	for _, f := range u.files {
+		n += estimate(f)
+	}
+	return n
+}
+
+// 圧縮前のサイズにヘッダーと、圧縮できないデータが圧縮で増える分を加えた見積もり
+func estimate(f file) int64 {
+	return f.info.Size() + f.info.Size()/1024 + entryOverhead + int64(len(f.name))*2
+}
+
+// アーカイブを作成し、作成したアーカイブのマニフェストを返す
+func Build(opts Options) ([]Manifest, error) {
+	if !slices.Contains(Formats, opts.Format) {
+		return nil, fmt.Errorf("unsupported archive format %q (%s)", opts.Format, strings.Join(Formats, ", "))
+	}
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(filepath.Clean(opts.SourceDir))
+	}
+
+	units, err := collectUnits(opts)
+	if err != nil {
+		return nil, err
+	}
+	chunks := split(units, opts.MaxDirs, opts.MaxBytes)
+	if err := checkMaxBytes(chunks, opts.MaxBytes); err != nil {
+		return nil, err
+	}
+	if opts.Part > len(chunks) {
+		return nil, fmt.Errorf("part %d does not exist (%d parts)", opts.Part, len(chunks))
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0777); err != nil {
+		return nil, err
+	}
+	// 全てのアーカイブを作り直す場合は、分割数が減った場合に古いアーカイブが残らないよう削除する
+	if opts.Part <= 0 {
+		if err := removeOld(opts.OutputDir, name, opts.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	var manifests []Manifest
+	for i, chunk := range chunks {
+		if opts.Part > 0 && opts.Part != i+1 {
+			continue
+		}
+		archivePath := filepath.Join(opts.OutputDir, fmt.Sprintf("%s_%d.%s", name, i+1, opts.Format))
+		m, err := writeArchive(archivePath, opts.Format, chunk, opts.MaxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", archivePath, err)
+		}
+		m.Part, m.Parts = i+1, len(chunks)
+		if err := writeSidecars(archivePath, m); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// SourceDir直下のディレクトリを単位として、含まれるファイルを集める
+// 直下のファイル (ジャーナルやアセットのレポート) は対象外とする
+func collectUnits(opts Options) ([]unit, error) {
+	entries, err := os.ReadDir(opts.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+	// アーカイブ内のパスは SourceDir のディレクトリ名から始める (zip -r output/<group> と同じ)
+	root := filepath.Base(filepath.Clean(opts.SourceDir))
+
+	var units []unit
+	for _, e := range entries {
+		if !e.IsDir() || excluded(e.Name(), opts.Exclude) {
+			continue
+		}
+		u := unit{name: e.Name()}
+		err := filepath.WalkDir(filepath.Join(opts.SourceDir, e.Name()), func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(opts.SourceDir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if excluded(rel, opts.Exclude) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			u.files = append(u.files, file{abs: p, rel: rel, name: path.Join(root, rel), info: info})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(u.files) > 0 {
+			units = append(units, u)
+		}
+	}
+	return units, nil
+}
+
+func excluded(rel string, exclude []string) bool {
+	for _, s := range exclude {
+		if s != "" && strings.Contains(rel, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// 単位をディレクトリ数とサイズの上限ごとに分ける
+// 1つでサイズの上限を超える単位は、その中のディレクトリとファイルに分けてから詰める
+func split(units []unit, maxDirs int, maxBytes int64) [][]unit {
+	var expanded []unit
+	for _, u := range units {
+		expanded = append(expanded, expand(u, maxBytes)...)
+	}
+
+	var chunks [][]unit
+	var current []unit
+	var size int64
+	for _, u := range expanded {
+		cost := u.cost()
+		full := maxDirs > 0 && len(current) >= maxDirs
+		over := maxBytes > 0 && size+cost > maxBytes
+		if len(current) > 0 && (full || over) {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, u)
+		size += cost
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// アーカイブを書き込む前に、ファイル1件でサイズの上限を超えるものがないか確認する
+// それ以上分けられないため、上限を上げるか対象外にするまでエラーにする
+func checkMaxBytes(chunks [][]unit, maxBytes int64) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+	for _, chunk := range chunks {
+		for _, u := range chunk {
+			if len(u.files) == 1 && u.cost() > maxBytes {
+				f := u.files[0]
+				return fmt.Errorf("%s (%d bytes, estimated %d bytes in the archive) does not fit in the max size %d bytes, raise the max size or exclude it", f.rel, f.info.Size(), u.cost(), maxBytes)
+			}
+		}
+	}
+	return nil
+}
+
+// サイズの上限を超える単位を、直下のディレクトリとファイルごとの単位に分ける
+// ファイル1件で上限を超える場合はそのまま1つの単位にする (checkMaxBytes でエラーにする)
+func expand(u unit, maxBytes int64) []unit {
+	if maxBytes <= 0 || u.cost() <= maxBytes {
+		return []unit{u}
+	}
+
+	// ファイル1件の単位はそれ以上分けられない
+	if len(u.files) == 1 && u.files[0].rel == u.name {
+		return []unit{u}
+	}
+
+	var children []unit
+	index := make(map[string]int)
+	for _, f := range u.files {
+		first, _, _ := strings.Cut(strings.TrimPrefix(f.rel, u.name+"/"), "/")
+		child := u.name + "/" + first
+		i, ok := index[child]
+		if !ok {
+			i = len(children)
+			index[child] = i
+			children = append(children, unit{name: child})
+		}
+		children[i].files = append(children[i].files, f)
+	}
+
+	var units []unit
+	for _, c := range children {
+		units = append(units, expand(c, maxBytes)...)
+	}
+	return units
+}
+
+// 以前に作成した同じ名前と形式のアーカイブとサイドカーを削除する
+func removeOld(dir, name, format string) error {
+	old, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s_[0-9]*.%s*", name, format)))
+	if err != nil {
+		return err
+	}
+	for _, p := range old {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// マニフェストとSHA-256のサイドカーを保存する
+func writeSidecars(archivePath string, m Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(archivePath+".manifest.json", b, 0666); err != nil {
+		return err
+	}
+	sum := fmt.Sprintf("%s  %s\n", m.SHA256, m.Archive)
+	return os.WriteFile(archivePath+".sha256", []byte(sum), 0666)
+}
+
+// アーカイブを一時ファイルに書き込み、完了したら配置する
+// 分割は見積もりで行うため、書き込んだサイズが上限を超えた場合は配置せずにエラーにする
+func writeArchive(archivePath, format string, chunk []unit, maxBytes int64) (Manifest, error) {
+	m := Manifest{
+		Archive:   filepath.Base(archivePath),
+		Format:    format,
+		CreatedAt: time.Now(),
+	}
+
+	tmpPath := archivePath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.Remove(tmpPath)
+	defer out.Close()
+
+	archiveHash := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(out, archiveHash)}
+	w, err := newEntryWriter(format, counter)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for _, u := range chunk {
+		m.Dirs = append(m.Dirs, u.name)
+		for _, f := range u.files {
+			sum, err := addFile(w, f)
+			if err != nil {
+				return Manifest{}, err
+			}
+			m.Files = append(m.Files, ManifestFile{Path: f.name, Size: f.info.Size(), SHA256: sum})
+		}
+	}
+	if err := w.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if maxBytes > 0 && counter.n > maxBytes {
+		var estimated int64
+		for _, u := range chunk {
+			estimated += u.cost()
+		}
+		return Manifest{}, fmt.Errorf("archive is %d bytes (estimated %d bytes) and exceeds the max size %d bytes, lower the max size to leave a margin", counter.n, estimated, maxBytes)
+	}
+	if err := out.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return Manifest{}, err
+	}
+
+	m.Size = counter.n
+	m.SHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+	return m, nil
+}
+
+// ファイルをアーカイブに追加し、内容のSHA-256を返す
+func addFile(w entryWriter, f file) (string, error) {
+	src, err := os.Open(f.abs)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := w.Create(f.name, f.info)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		return "", fmt.Errorf("failed to add %s: %w", f.name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
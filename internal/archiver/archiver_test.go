@@ -0,0 +1,205 @@
+package archiver
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// relからファイルの内容へのマップでディレクトリを作成する
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "output")
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuild(t *testing.T) {
+	files := map[string]string{
+		"a/1/x.md":        strings.Repeat("a", 3000),
+		"a/2/x.md":        strings.Repeat("b", 3000),
+		"b/1/x.md":        "b",
+		"c/1/x.md":        "c",
+		"c/1/skip.tmp":    "tmp",
+		"journal.json":    "{}",
+		"d/1/secret.json": "secret",
+	}
+	tests := []struct {
+		name     string
+		opts     Options
+		wantDirs [][]string // アーカイブごとの分割の単位
+		wantErr  string
+	}{
+		{
+			name:     "無制限",
+			opts:     Options{Exclude: []string{"d/", ".tmp"}},
+			wantDirs: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:     "ディレクトリ数",
+			opts:     Options{MaxDirs: 2, Exclude: []string{"d/", ".tmp"}},
+			wantDirs: [][]string{{"a", "b"}, {"c"}},
+		},
+		{
+			name:     "サイズの上限を超えるディレクトリは分ける",
+			opts:     Options{MaxBytes: 5000, Exclude: []string{"d/", ".tmp"}},
+			wantDirs: [][]string{{"a/1"}, {"a/2"}, {"b", "c"}},
+		},
+		{
+			name:     "指定した番号のみ",
+			opts:     Options{MaxDirs: 1, Part: 2, Exclude: []string{"d/", ".tmp"}},
+			wantDirs: [][]string{{"b"}},
+		},
+		{
+			name:    "存在しない番号",
+			opts:    Options{MaxDirs: 1, Part: 9},
+			wantErr: "part 9 does not exist",
+		},
+		{
+			name:    "ファイル1件で上限を超える",
+			opts:    Options{MaxBytes: 2000},
+			wantErr: "a/1/x.md (3000 bytes, estimated 4568 bytes in the archive) does not fit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeTree(t, files)
+			out := filepath.Join(t.TempDir(), "archives")
+			opts := tt.opts
+			opts.SourceDir, opts.OutputDir, opts.Format = src, out, FormatZip
+
+			manifests, err := Build(opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				// エラーの場合はアーカイブを作成しない
+				if _, err := os.Stat(out); !os.IsNotExist(err) {
+					t.Errorf("output dir exists: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var dirs [][]string
+			for _, m := range manifests {
+				dirs = append(dirs, m.Dirs)
+				checkZip(t, filepath.Join(out, m.Archive), m)
+			}
+			if !slices.EqualFunc(dirs, tt.wantDirs, slices.Equal) {
+				t.Errorf("dirs = %v, want %v", dirs, tt.wantDirs)
+			}
+		})
+	}
+}
+
+// zipの内容がマニフェストと一致し、サイドカーがあることを確認する
+func checkZip(t *testing.T, path string, m Manifest) {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	var want []string
+	for _, f := range m.Files {
+		if !strings.HasPrefix(f.Path, "output/") {
+			t.Errorf("path %s does not start with the source dir name", f.Path)
+		}
+		want = append(want, f.Path)
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("%s: entries = %v, want %v", m.Archive, names, want)
+	}
+
+	sum, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sum) != m.SHA256+"  "+m.Archive+"\n" {
+		t.Errorf("sha256 sidecar = %q", sum)
+	}
+	if _, err := os.Stat(path + ".manifest.json"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuildFormats(t *testing.T) {
+	src := writeTree(t, map[string]string{"a/1/x.md": "x"})
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			out := t.TempDir()
+			manifests, err := Build(Options{SourceDir: src, OutputDir: out, Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(manifests) != 1 || manifests[0].Archive != "output_1."+format {
+				t.Fatalf("manifests = %+v", manifests)
+			}
+			if info, err := os.Stat(filepath.Join(out, manifests[0].Archive)); err != nil || info.Size() != manifests[0].Size {
+				t.Errorf("archive size = %v, %v, want %d", info, err, manifests[0].Size)
+			}
+		})
+	}
+
+	if _, err := Build(Options{SourceDir: src, OutputDir: t.TempDir(), Format: "rar"}); err == nil {
+		t.Error("unsupported format: err = nil")
+	}
+}
+
+// 分割数が減った場合は古いアーカイブを削除する
+func TestBuildRemovesOldParts(t *testing.T) {
+	src := writeTree(t, map[string]string{"a/x": "a", "b/x": "b", "c/x": "c"})
+	out := t.TempDir()
+	if _, err := Build(Options{SourceDir: src, OutputDir: out, Format: FormatZip, MaxDirs: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(Options{SourceDir: src, OutputDir: out, Format: FormatZip}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := filepath.Glob(filepath.Join(out, "*.zip"))
+	if len(got) != 1 || filepath.Base(got[0]) != "output_1.zip" {
+		t.Errorf("archives = %v, want only output_1.zip", got)
+	}
+}
+
+// 書き込んだアーカイブが上限を超えた場合は配置せず、一時ファイルも残さない
+func TestWriteArchiveExceedsMaxBytes(t *testing.T) {
+	src := writeTree(t, map[string]string{"a/1/x.md": strings.Repeat("a", 3000)})
+	units, err := collectUnits(Options{SourceDir: src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	archivePath := filepath.Join(out, "output_1.zip")
+
+	_, err = writeArchive(archivePath, FormatZip, units, 100)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the max size 100 bytes") {
+		t.Fatalf("err = %v", err)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) > 0 {
+		t.Errorf("files remain: %v", entries)
+	}
+
+	if _, err := writeArchive(archivePath, FormatZip, units, 10000); err != nil {
+		t.Fatal(err)
+	}
+}
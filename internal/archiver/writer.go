@@ -0,0 +1,81 @@
+package archiver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// アーカイブの形式ごとの書き込み
+type entryWriter interface {
+	// ファイルのエントリを作成し、内容を書き込むWriterを返す
+	Create(name string, info os.FileInfo) (io.Writer, error)
+	Close() error
+}
+
+func newEntryWriter(format string, w io.Writer) (entryWriter, error) {
+	switch format {
+	case FormatZip:
+		return zipWriter{zip.NewWriter(w)}, nil
+	case FormatTarGz:
+		return newTarWriter(gzip.NewWriter(w)), nil
+	case FormatTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return newTarWriter(zw), nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (z zipWriter) Create(name string, info os.FileInfo) (io.Writer, error) {
+	h, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, err
+	}
+	h.Name = name
+	h.Method = zip.Deflate
+	return z.CreateHeader(h)
+}
+
+// tarと圧縮 (gzip, zstd) のWriter
+type tarWriter struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser
+}
+
+func newTarWriter(compressor io.WriteCloser) *tarWriter {
+	return &tarWriter{tw: tar.NewWriter(compressor), compressor: compressor}
+}
+
+func (t *tarWriter) Create(name string, info os.FileInfo) (io.Writer, error) {
+	h, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil, err
+	}
+	h.Name = name
+	// 環境に依存する所有者の情報は含めない
+	h.Uid, h.Gid, h.Uname, h.Gname = 0, 0, "", ""
+	if err := t.tw.WriteHeader(h); err != nil {
+		return nil, err
+	}
+	return t.tw, nil
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.compressor.Close()
+}
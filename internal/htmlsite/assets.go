@@ -0,0 +1,100 @@
+package htmlsite
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/qiita_export/repository"
+)
+
+// アセットを参照するHTMLの属性
+var assetAttrRegexp = regexp.MustCompile(`(?i)(\s(?:src|href|data-src|data-canonical-src|poster)\s*=\s*)(["'])([^"']*)(["'])`)
+
+// HTMLが参照するアセットのうち、エクスポート済みのものを記事のページのディレクトリにコピーし、相対パスに置換する
+// エクスポートしていないURL (外部のリンクなど) はそのまま残す
+func (g *generator) localizeAssets(body, artDir, pageDir string) (string, error) {
+	copied := make(map[string]string) // コピー元 → ページからの相対パス
+	var copyErr error
+	body = assetAttrRegexp.ReplaceAllStringFunc(body, func(m string) string {
+		sub := assetAttrRegexp.FindStringSubmatch(m)
+		src := g.findAsset(sub[3], artDir)
+		if src == "" || copyErr != nil {
+			return m
+		}
+
+		rel, ok := copied[src]
+		if !ok {
+			name := filepath.Base(src)
+			if err := copyFile(src, filepath.Join(pageDir, name)); err != nil {
+				copyErr = err
+				return m
+			}
+			rel = url.PathEscape(name)
+			copied[src] = rel
+		}
+		return sub[1] + sub[2] + rel + sub[4]
+	})
+
+	return body, copyErr
+}
+
+// 属性の値が参照するエクスポート済みのアセットのファイルを探す, 見つからない場合は空を返す
+// URLの場合は記事のディレクトリとアセットストア、相対パスの場合は記事のディレクトリからのパスで探す
+func (g *generator) findAsset(value, artDir string) string {
+	u, err := url.Parse(value)
+	if err != nil || value == "" || strings.HasPrefix(value, "#") {
+		return ""
+	}
+
+	if u.Scheme == "http" || u.Scheme == "https" {
+		if p, ok := repository.FindAssetFile(artDir, value); ok {
+			return p
+		}
+		if g.store != nil {
+			if e, ok := g.store.Lookup(value); ok {
+				return g.store.FilePath(e)
+			}
+		}
+		return ""
+	}
+	if u.Scheme != "" || u.Host != "" || path.IsAbs(u.Path) || u.Path == "" {
+		return ""
+	}
+
+	// -rewrite_rendered_body でローカルのパスに置換済みの場合
+	p := filepath.Join(artDir, filepath.FromSlash(u.Path))
+	if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+		return p
+	}
+	return ""
+}
+
+// ファイルをコピーする, 同じファイルシステムの場合はハードリンクにする
+func copyFile(src, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
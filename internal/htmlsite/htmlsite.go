@@ -0,0 +1,459 @@
+// Package htmlsite はエクスポートしたディレクトリから、サーバーなしで閲覧できる静的なHTMLのサイトを生成する
+//
+//	<output>/index.html                  全記事, グループ, タグ, 投稿者の一覧
+//	<output>/groups/<name>.html          グループごとの記事一覧
+//	<output>/tags/<name>.html            タグごとの記事一覧
+//	<output>/authors/<id>.html           投稿者ごとの記事一覧
+//	<output>/articles/<id>/index.html    記事, コメント, 絵文字リアクション, アセット
+//
+// file:// で開けるよう、リンクは全て相対パスで、ディレクトリではなくファイルを指す
+package htmlsite
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// 生成するサブディレクトリ, 再生成時に削除して作り直す
+var generatedDirs = []string{"articles", "groups", "tags", "authors"}
+
+// Options はサイトの生成の設定
+type Options struct {
+	ExportDir     string // エクスポート先のディレクトリ (<group>/<id>/*_metadata.json)
+	OutputDir     string // サイトの出力先
+	AssetStoreDir string // エクスポート時に -asset_store を指定した場合のアセットストア
+	Title         string // サイトのタイトル
+}
+
+// 記事一覧に表示する記事
+type articleSummary struct {
+	ID      string
+	Title   string
+	Href    string // サイトのルートからの相対パス
+	Author  string
+	Group   string
+	Created string
+
+	createdAt time.Time
+}
+
+// グループ, タグ, 投稿者ごとの一覧ページ
+type term struct {
+	Name     string
+	Href     string // URLエンコードしたパス
+	Articles []articleSummary
+
+	path string // サイトのルートからのファイルのパス
+}
+
+// 記事ページに表示する記事
+type articlePage struct {
+	Title      string
+	URL        string
+	Author     string
+	AuthorHref string
+	Group      string
+	GroupHref  string
+	Created    string
+	Updated    string
+	Tags       []term
+	Body       template.HTML
+	Reactions  []reactionSummary
+	Comments   []commentView
+}
+
+type commentView struct {
+	Author    string
+	Created   string
+	Body      template.HTML
+	Reactions []reactionSummary
+}
+
+// 絵文字ごとのリアクション数
+type reactionSummary struct {
+	Name  string
+	Count int
+	Users string
+}
+
+// テンプレートに渡す値
+type pageData struct {
+	SiteTitle   string
+	PageTitle   string
+	Root        string // サイトのルートへの相対パス
+	GeneratedAt string
+	Articles    []articleSummary
+	Groups      []*term
+	Tags        []*term
+	Authors     []*term
+	Article     *articlePage
+}
+
+// サイトを生成する
+func Generate(opts Options) error {
+	if opts.Title == "" {
+		opts.Title = "Qiita Team Archive"
+	}
+	if err := checkOutputDir(opts.ExportDir, opts.OutputDir); err != nil {
+		return err
+	}
+
+	exported, err := repository.LoadExportedArticles(opts.ExportDir)
+	if err != nil {
+		return fmt.Errorf("failed to load exported articles: %w", err)
+	}
+
+	var store *repository.AssetStore
+	if opts.AssetStoreDir != "" {
+		if store, err = repository.OpenAssetStore(opts.AssetStoreDir); err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := template.ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		return err
+	}
+	pages := make(map[string]*template.Template)
+	for _, name := range []string{"index", "term", "article"} {
+		t, err := template.Must(tmpl.Clone()).ParseFS(templateFS, "templates/"+name+".html")
+		if err != nil {
+			return err
+		}
+		pages[name] = t
+	}
+
+	for _, dir := range generatedDirs {
+		if err := os.RemoveAll(filepath.Join(opts.OutputDir, dir)); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0777); err != nil {
+		return err
+	}
+
+	g := &generator{
+		opts:        opts,
+		store:       store,
+		pages:       pages,
+		generatedAt: time.Now().Format(time.DateTime),
+		groups:      newTermIndex("groups"),
+		tags:        newTermIndex("tags"),
+		authors:     newTermIndex("authors"),
+	}
+
+	var summaries []articleSummary
+	for _, e := range exported {
+		s, err := g.writeArticle(e)
+		if err != nil {
+			return fmt.Errorf("failed to generate article %s: %w", e.Article.ID, err)
+		}
+		summaries = append(summaries, s)
+	}
+	sortArticles(summaries)
+
+	for _, idx := range []*termIndex{g.groups, g.tags, g.authors} {
+		for _, t := range idx.sorted() {
+			sortArticles(t.Articles)
+			if err := g.render("term", t.path, pageData{PageTitle: idx.label(t), Articles: t.Articles}); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = g.render("index", "index.html", pageData{
+		PageTitle: "トップ",
+		Articles:  summaries,
+		Groups:    g.groups.sorted(),
+		Tags:      g.tags.sorted(),
+		Authors:   g.authors.sorted(),
+	})
+	if err != nil {
+		return err
+	}
+
+	css, err := templateFS.ReadFile("templates/style.css")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(opts.OutputDir, "style.css"), css, 0666); err != nil {
+		return err
+	}
+
+	slog.Info("サイトを生成しました", "articles", len(summaries), "index", filepath.Join(opts.OutputDir, "index.html"))
+	return nil
+}
+
+// 出力先がエクスポート先と同じ, またはエクスポート先を含む場合はエラーにする
+// 再生成時に出力先のサブディレクトリを削除するため、エクスポートした記事を削除しないようにする
+func checkOutputDir(exportDir, outputDir string) error {
+	absExport, err := filepath.Abs(exportDir)
+	if err != nil {
+		return err
+	}
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absOutput, absExport)
+	if err != nil {
+		return nil // Windowsで別のドライブの場合
+	}
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("-out %s must not be the same as or contain -dir %s", outputDir, exportDir)
+	}
+	return nil
+}
+
+type generator struct {
+	opts        Options
+	store       *repository.AssetStore
+	pages       map[string]*template.Template
+	generatedAt string
+
+	groups  *termIndex
+	tags    *termIndex
+	authors *termIndex
+}
+
+// 記事のページを生成し、一覧に表示する内容を返す
+func (g *generator) writeArticle(e repository.ExportedArticle) (articleSummary, error) {
+	a := e.Article
+	href := path.Join("articles", a.ID, "index.html")
+	pageDir := filepath.Join(g.opts.OutputDir, "articles", a.ID)
+	if err := os.MkdirAll(pageDir, 0777); err != nil {
+		return articleSummary{}, err
+	}
+
+	body, err := g.articleBody(e)
+	if err != nil {
+		return articleSummary{}, err
+	}
+	body, err = g.localizeAssets(body, e.Dir, pageDir)
+	if err != nil {
+		return articleSummary{}, err
+	}
+
+	author := userName(a.User)
+	group := ""
+	if a.Group != nil {
+		group = a.Group.Name
+	}
+	s := articleSummary{
+		ID:        a.ID,
+		Title:     a.Title,
+		Href:      href,
+		Author:    author,
+		Group:     group,
+		Created:   formatTime(a.CreatedAt),
+		createdAt: a.CreatedAt,
+	}
+
+	page := &articlePage{
+		Title:      a.Title,
+		URL:        a.URL,
+		Author:     author,
+		AuthorHref: g.authors.add(author, author, s).Href,
+		Group:      group,
+		Created:    formatTime(a.CreatedAt),
+		Updated:    formatTime(a.UpdatedAt),
+		Body:       template.HTML(body),
+		Reactions:  summarizeReactions(a.EmojiReactions),
+	}
+	if a.Group != nil {
+		page.GroupHref = g.groups.add(cmp.Or(a.Group.URLName, a.Group.Name), a.Group.Name, s).Href
+	}
+	for _, tag := range a.Tags {
+		// Qiitaのタグは大文字小文字を区別しない
+		t := g.tags.add(strings.ToLower(tag.Name), tag.Name, s)
+		page.Tags = append(page.Tags, *t)
+	}
+	for _, c := range a.Comments {
+		commentBody := c.RenderedBody
+		if commentBody == "" {
+			if commentBody, err = renderMarkdown(c.Body); err != nil {
+				return articleSummary{}, err
+			}
+		}
+		page.Comments = append(page.Comments, commentView{
+			Author:    userName(&c.User),
+			Created:   formatTime(c.CreatedAt),
+			Body:      template.HTML(commentBody),
+			Reactions: summarizeReactions(c.EmojiReactions),
+		})
+	}
+
+	return s, g.render("article", href, pageData{PageTitle: a.Title, Article: page})
+}
+
+// 記事の本文のHTML
+// rendered_bodyがない場合は、保存したMarkdown (アセットはローカルのパス) を変換する
+func (g *generator) articleBody(e repository.ExportedArticle) (string, error) {
+	if e.Article.RenderedBody != "" {
+		return e.Article.RenderedBody, nil
+	}
+	md, err := os.ReadFile(e.MarkdownPath())
+	if err != nil {
+		md = []byte(e.Article.Body)
+	}
+	return renderMarkdown(string(md))
+}
+
+// テンプレートからページを生成する, pagePathはサイトのルートからのファイルのパス
+func (g *generator) render(name, pagePath string, data pageData) error {
+	data.SiteTitle = g.opts.Title
+	data.GeneratedAt = g.generatedAt
+	data.Root = strings.Repeat("../", strings.Count(pagePath, "/"))
+
+	p := filepath.Join(g.opts.OutputDir, filepath.FromSlash(pagePath))
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := g.pages[name].ExecuteTemplate(&buf, "layout", data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", pagePath, err)
+	}
+	return writeFileAtomic(p, buf.Bytes(), 0666)
+}
+
+// 一時ファイルに書き込んでからリネームし、書きかけのページが残らないようにする
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// グループ, タグ, 投稿者ごとの記事の一覧
+type termIndex struct {
+	dir   string
+	terms map[string]*term
+	used  map[string]bool
+}
+
+func newTermIndex(dir string) *termIndex {
+	return &termIndex{dir: dir, terms: make(map[string]*term), used: make(map[string]bool)}
+}
+
+// keyの一覧に記事を追加する
+// ページのファイル名はkeyから決め、ファイル名が衝突した場合はkeyのハッシュを付与する
+func (idx *termIndex) add(key, name string, s articleSummary) *term {
+	t, ok := idx.terms[key]
+	if !ok {
+		fileName := pageFileName(key)
+		if idx.used[strings.ToLower(fileName)] {
+			sum := sha256.Sum256([]byte(key))
+			fileName += "-" + hex.EncodeToString(sum[:4])
+		}
+		// 大文字小文字を区別しないファイルシステムでも衝突しないよう、小文字で判定する
+		idx.used[strings.ToLower(fileName)] = true
+
+		t = &term{
+			Name: name,
+			Href: path.Join(idx.dir, url.PathEscape(fileName)+".html"),
+			path: path.Join(idx.dir, fileName+".html"),
+		}
+		idx.terms[key] = t
+	}
+	t.Articles = append(t.Articles, s)
+	return t
+}
+
+func (idx *termIndex) sorted() []*term {
+	terms := make([]*term, 0, len(idx.terms))
+	for _, t := range idx.terms {
+		terms = append(terms, t)
+	}
+	slices.SortFunc(terms, func(a, b *term) int {
+		return cmp.Or(len(b.Articles)-len(a.Articles), strings.Compare(a.Name, b.Name))
+	})
+	return terms
+}
+
+// 一覧ページのタイトル
+func (idx *termIndex) label(t *term) string {
+	switch idx.dir {
+	case "groups":
+		return "グループ: " + t.Name
+	case "tags":
+		return "タグ: " + t.Name
+	default:
+		return "投稿者: " + t.Name
+	}
+}
+
+// ファイル名として使用できない文字
+var invalidFileNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_", "#", "_", "%", "_")
+
+func pageFileName(key string) string {
+	name := invalidFileNameReplacer.Replace(key)
+	if name == "" || name == "." || name == ".." {
+		name = "_"
+	}
+	return name
+}
+
+// 作成日時の新しい順に並べる
+func sortArticles(articles []articleSummary) {
+	slices.SortStableFunc(articles, func(a, b articleSummary) int {
+		return cmp.Or(b.createdAt.Compare(a.createdAt), strings.Compare(a.ID, b.ID))
+	})
+}
+
+// 絵文字ごとにリアクションを集計し、多い順に並べる
+func summarizeReactions(reactions []models.EmojiReaction) []reactionSummary {
+	var summaries []reactionSummary
+	index := make(map[string]int)
+	for _, r := range reactions {
+		i, ok := index[r.Name]
+		if !ok {
+			i = len(summaries)
+			index[r.Name] = i
+			summaries = append(summaries, reactionSummary{Name: r.Name})
+		}
+		summaries[i].Count++
+		if summaries[i].Users != "" {
+			summaries[i].Users += ", "
+		}
+		summaries[i].Users += r.User.ID
+	}
+	slices.SortStableFunc(summaries, func(a, b reactionSummary) int {
+		return b.Count - a.Count
+	})
+	return summaries
+}
+
+func userName(u *models.User) string {
+	if u == nil || u.ID == "" {
+		return "unknown"
+	}
+	return u.ID
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
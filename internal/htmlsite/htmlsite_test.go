@@ -0,0 +1,231 @@
+package htmlsite
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// エクスポート先のディレクトリに記事のメタデータとMarkdownを保存する
+func writeExported(t *testing.T, exportDir, group string, v models.Article, markdown string) string {
+	t.Helper()
+	dir := filepath.Join(exportDir, group, v.ID)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, v.Title+"_metadata.json"), b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, v.Title+".md"), []byte(markdown), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGenerate(t *testing.T) {
+	root := t.TempDir()
+	exportDir, outputDir := filepath.Join(root, "output"), filepath.Join(root, "archive")
+
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	assetURL := "https://example.qiita.com/files/a.png"
+	a := models.Article{
+		ID: "item1", Title: "設計", CreatedAt: created, UpdatedAt: created,
+		RenderedBody: `<p><img src="` + assetURL + `"><a href="https://example.com/">外部</a></p>`,
+		User:         &models.User{ID: "alice"},
+		Group:        &models.Group{Name: "開発", URLName: "dev"},
+		Tags:         []models.Tagging{{Name: "Go"}},
+		Comments:     []models.Comment{{Body: "**コメント**", User: models.User{ID: "bob"}}},
+	}
+	artDir := writeExported(t, exportDir, "dev", a, "")
+	if err := os.WriteFile(filepath.Join(artDir, "a.png"), testPNG, 0666); err != nil {
+		t.Fatal(err)
+	}
+	// rendered_bodyがない記事は保存したMarkdownを変換する
+	b := models.Article{
+		ID: "item2", Title: "雑談", CreatedAt: created.Add(time.Hour), UpdatedAt: created,
+		User: &models.User{ID: "bob"}, Tags: []models.Tagging{{Name: "go"}},
+	}
+	writeExported(t, exportDir, "_public", b, "# 見出し")
+
+	// 前回の生成で残ったページは削除する
+	stale := filepath.Join(outputDir, "articles", "deleted", "index.html")
+	if err := os.MkdirAll(filepath.Dir(stale), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Generate(Options{ExportDir: exportDir, OutputDir: outputDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := readFile(t, filepath.Join(outputDir, "index.html"))
+	for _, want := range []string{"Qiita Team Archive", `href="articles/item1/index.html"`, `href="articles/item2/index.html"`, `href="groups/dev.html"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html does not contain %s", want)
+		}
+	}
+	// 新しい記事から並べる
+	if strings.Index(index, "item2") > strings.Index(index, "item1") {
+		t.Error("articles are not sorted by created_at desc")
+	}
+
+	page := readFile(t, filepath.Join(outputDir, "articles", "item1", "index.html"))
+	for _, want := range []string{`<img src="a.png">`, `href="https://example.com/"`, "<strong>コメント</strong>", `href="../../groups/dev.html"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("article page does not contain %s", want)
+		}
+	}
+	if got := readFile(t, filepath.Join(outputDir, "articles", "item1", "a.png")); got != string(testPNG) {
+		t.Errorf("asset = %q", got)
+	}
+	if page := readFile(t, filepath.Join(outputDir, "articles", "item2", "index.html")); !strings.Contains(page, "<h1>見出し</h1>") {
+		t.Errorf("markdown is not rendered: %s", page)
+	}
+
+	// Goとgoは同じタグとして1ページにまとめる
+	if tag := readFile(t, filepath.Join(outputDir, "tags", "go.html")); !strings.Contains(tag, "item1") || !strings.Contains(tag, "item2") {
+		t.Errorf("tag page = %s", tag)
+	}
+	for _, p := range []string{"style.css", filepath.Join("authors", "alice.html")} {
+		if _, err := os.Stat(filepath.Join(outputDir, p)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale page remains: %v", err)
+	}
+
+	// 一時ファイルは残さない
+	err := filepath.WalkDir(outputDir, func(path string, d os.DirEntry, err error) error {
+		if strings.HasSuffix(path, ".tmp") {
+			t.Errorf("temporary file remains: %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 出力先がエクスポート先と同じ, またはエクスポート先を含む場合は何も削除せずにエラーにする
+func TestGenerateRejectsOutputDir(t *testing.T) {
+	root := t.TempDir()
+	exportDir := filepath.Join(root, "output")
+	writeExported(t, exportDir, "articles", models.Article{ID: "item1", Title: "記事"}, "")
+
+	tests := []struct {
+		name    string
+		out     string
+		wantErr bool
+	}{
+		{"同じディレクトリ", exportDir, true},
+		{"末尾のスラッシュ", exportDir + string(filepath.Separator), true},
+		{"親ディレクトリ", root, true},
+		{"エクスポート先の中", filepath.Join(exportDir, "_html"), false},
+		{"別のディレクトリ", filepath.Join(root, "archive"), false},
+		{"名前の前方が一致するディレクトリ", exportDir + "-site", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOutputDir(exportDir, tt.out); (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputDir(%s) = %v, wantErr %v", tt.out, err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := Generate(Options{ExportDir: exportDir, OutputDir: exportDir}); err == nil {
+		t.Fatal("Generate() with -out equal to -dir succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, "articles", "item1")); err != nil {
+		t.Errorf("exported article is removed: %v", err)
+	}
+}
+
+func TestLocalizeAssets(t *testing.T) {
+	root := t.TempDir()
+	artDir, pageDir := filepath.Join(root, "dev", "item1"), filepath.Join(root, "archive", "articles", "item1")
+	for _, dir := range []string{artDir, pageDir, filepath.Join(artDir, "sub")} {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.png", "画像 1.png", filepath.Join("sub", "c.png")} {
+		if err := os.WriteFile(filepath.Join(artDir, name), testPNG, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// アセットストアにのみあるアセット
+	store, err := repository.OpenAssetStore(filepath.Join(root, "_assets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeURL := "https://example.qiita.com/files/store.png"
+	storePath := filepath.Join(store.Dir(), "sha256", "ab", "abcd.png")
+	if err := os.MkdirAll(filepath.Dir(storePath), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(storePath, testPNG, 0666); err != nil {
+		t.Fatal(err)
+	}
+	index := `{"` + storeURL + `": {"sha256": "abcd", "path": "sha256/ab/abcd.png", "size": 12}}`
+	if err := os.WriteFile(filepath.Join(store.Dir(), repository.AssetStoreIndexFileName), []byte(index), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = repository.OpenAssetStore(store.Dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, body, want string
+		wantFile         string
+	}{
+		{"記事のディレクトリのアセット", `<img src="https://example.qiita.com/files/a.png">`, `<img src="a.png">`, "a.png"},
+		{"ファイル名のエンコード", `<img src='https://example.qiita.com/files/%E7%94%BB%E5%83%8F%201.png'>`, `<img src='%E7%94%BB%E5%83%8F%201.png'>`, "画像 1.png"},
+		{"置換済みの相対パス", `<a href="sub/c.png">c</a>`, `<a href="c.png">c</a>`, "c.png"},
+		{"アセットストア", `<img data-src="` + storeURL + `">`, `<img data-src="abcd.png">`, "abcd.png"},
+		{"エクスポートしていないURL", `<img src="https://example.com/x.png">`, `<img src="https://example.com/x.png">`, ""},
+		{"記事の外を指す相対パス", `<a href="../item2/a.png">`, `<a href="../item2/a.png">`, ""},
+		{"アンカー", `<a href="#a.png">`, `<a href="#a.png">`, ""},
+	}
+	g := &generator{store: store}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.localizeAssets(tt.body, artDir, pageDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("localizeAssets() = %s, want %s", got, tt.want)
+			}
+			if tt.wantFile != "" {
+				if b, err := os.ReadFile(filepath.Join(pageDir, tt.wantFile)); err != nil || string(b) != string(testPNG) {
+					t.Errorf("copied file = %q, %v", b, err)
+				}
+			}
+		})
+	}
+}
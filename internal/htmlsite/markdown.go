@@ -0,0 +1,24 @@
+package htmlsite
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// rendered_bodyがない場合にMarkdownをHTMLに変換する
+// QiitaのMarkdownはHTMLの埋め込みを許可しているため、そのまま出力する
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+func renderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,8 @@
+package search
+
+import _ "embed"
+
+// 検索ページ, インデックスと同じディレクトリに置き、file:// で開いて検索する
+//
+//go:embed search.html
+var searchPage []byte
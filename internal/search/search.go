@@ -0,0 +1,347 @@
+// Package search はエクスポートした記事の全文検索のインデックスを作成し、検索する
+//
+// 日本語は単語の区切りに空白を使わないため、文字のn-gram (1-gram, 2-gram) をトークンとする
+// インデックスはトークンのハッシュで分割したJSONのシャードとして保存し、CLIとオフラインのHTMLのページの両方から検索できる
+//
+//	<index>/meta.json          シャード数などの設定
+//	<index>/docs.json          記事の一覧
+//	<index>/shard-NNN.json     トークン → [記事の番号, スコア, 記事の番号, スコア, ...]
+//	<index>/shard-NNN.js       file:// で読み込むためのJSONP形式のシャード
+//	<index>/search.html        検索ページ
+package search
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/qiita_export/repository"
+)
+
+// インデックスの形式のバージョン, 形式を変更した場合は上げる
+const indexVersion = 1
+
+// デフォルトのシャード数
+const DefaultShards = 32
+
+// フィールドごとのスコアの重み
+const (
+	weightTitle   = 5
+	weightTag     = 4
+	weightAuthor  = 3
+	weightBody    = 1
+	weightComment = 1
+)
+
+// Document は検索結果として表示する記事
+type Document struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	URL     string   `json:"url"`  // 元の記事のURL
+	Path    string   `json:"path"` // 静的HTMLのアーカイブでの記事のパス
+	Group   string   `json:"group"`
+	Tags    []string `json:"tags"`
+	Author  string   `json:"author"`
+	Created string   `json:"created"`
+}
+
+// インデックスの設定
+type meta struct {
+	Version int `json:"version"`
+	Shards  int `json:"shards"`
+	Docs    int `json:"docs"`
+}
+
+// Options はインデックスの作成の設定
+type Options struct {
+	ExportDir string // エクスポートしたディレクトリ
+	IndexDir  string // インデックスの出力先
+	Shards    int    // シャード数, 0以下の場合はDefaultShards
+}
+
+// エクスポートしたディレクトリから検索のインデックスを作成する
+// タイトル, 本文, タグ, 投稿者, コメントを対象とする
+func Build(opts Options) error {
+	shards := opts.Shards
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+
+	exported, err := repository.LoadExportedArticles(opts.ExportDir)
+	if err != nil {
+		return fmt.Errorf("failed to load exported articles: %w", err)
+	}
+
+	docs := make([]Document, 0, len(exported))
+	postings := make([]map[string][]int, shards)
+	for i := range postings {
+		postings[i] = make(map[string][]int)
+	}
+
+	for docIndex, e := range exported {
+		a := e.Article
+		doc := Document{
+			ID:      a.ID,
+			Title:   a.Title,
+			URL:     a.URL,
+			Path:    path.Join("articles", a.ID, "index.html"),
+			Created: a.CreatedAt.Format("2006-01-02"),
+		}
+		if a.Group != nil {
+			doc.Group = a.Group.Name
+		}
+		if a.User != nil {
+			doc.Author = a.User.ID
+		}
+		for _, t := range a.Tags {
+			doc.Tags = append(doc.Tags, t.Name)
+		}
+		docs = append(docs, doc)
+
+		scores := make(map[string]int)
+		addTokens(scores, a.Title, weightTitle)
+		addTokens(scores, strings.Join(doc.Tags, " "), weightTag)
+		addTokens(scores, doc.Author, weightAuthor)
+		addTokens(scores, a.Body, weightBody)
+		for _, c := range a.Comments {
+			addTokens(scores, c.Body, weightComment)
+			addTokens(scores, c.User.ID, weightComment)
+		}
+		for token, score := range scores {
+			shard := postings[shardOf(token, shards)]
+			shard[token] = append(shard[token], docIndex, score)
+		}
+	}
+
+	if err := os.MkdirAll(opts.IndexDir, 0777); err != nil {
+		return err
+	}
+	// シャード数を変更した場合に古いシャードが残らないよう削除する
+	old, err := filepath.Glob(filepath.Join(opts.IndexDir, "shard-*"))
+	if err != nil {
+		return err
+	}
+	for _, p := range old {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+
+	if err := writeJSON(opts.IndexDir, "meta", meta{Version: indexVersion, Shards: shards, Docs: len(docs)}); err != nil {
+		return err
+	}
+	if err := writeJSON(opts.IndexDir, "docs", docs); err != nil {
+		return err
+	}
+	for i, p := range postings {
+		if err := writeJSON(opts.IndexDir, shardName(i), p); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(opts.IndexDir, "search.html"), searchPage, 0666); err != nil {
+		return err
+	}
+
+	slog.Info("インデックスを作成しました", "articles", len(docs), "dir", opts.IndexDir)
+	return nil
+}
+
+// JSONと、file:// のページからscript要素で読み込むためのJSONPの両方で保存する
+func writeJSON(dir, name string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), b, 0666); err != nil {
+		return err
+	}
+	js := fmt.Sprintf("qiitaSearchLoaded(%q, %s);\n", name, b)
+	return os.WriteFile(filepath.Join(dir, name+".js"), []byte(js), 0666)
+}
+
+func shardName(i int) string {
+	return fmt.Sprintf("shard-%03d", i)
+}
+
+// トークンが含まれるシャードの番号
+// 検索ページのJavaScriptと同じく、UTF-8のバイト列のFNV-1aで決める
+func shardOf(token string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(token))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// テキストのトークンごとに重みを加算する
+func addTokens(scores map[string]int, text string, weight int) {
+	for _, token := range Tokenize(text) {
+		scores[token] += weight
+	}
+}
+
+// テキストを正規化し、文字と数字の連続ごとに1-gramと2-gramに分割する
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, segment := range segments(text) {
+		for i := range segment {
+			tokens = append(tokens, string(segment[i]))
+			if i+1 < len(segment) {
+				tokens = append(tokens, string(segment[i:i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// 検索語のトークン
+// 1文字の語は1-gram, 2文字以上の語は2-gramで検索する
+func queryTokens(query string) []string {
+	var tokens []string
+	for _, segment := range segments(query) {
+		if len(segment) == 1 {
+			tokens = append(tokens, string(segment))
+			continue
+		}
+		for i := 0; i+1 < len(segment); i++ {
+			tokens = append(tokens, string(segment[i:i+2]))
+		}
+	}
+	slices.Sort(tokens)
+	return slices.Compact(tokens)
+}
+
+// 小文字と半角に揃え、文字と数字の連続に分割する
+func segments(text string) [][]rune {
+	var segments [][]rune
+	var current []rune
+	for _, r := range text {
+		r = normalizeRune(r)
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			current = append(current, r)
+			continue
+		}
+		if len(current) > 0 {
+			segments = append(segments, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// 全角英数字を半角に変換し、小文字にする
+// 検索ページのJavaScriptの normalize と同じ結果になるようにする
+// (ブラウザとGoのUnicodeのバージョンが異なる場合、新しく追加された文字の小文字は一致しないことがある)
+func normalizeRune(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+// Result は検索結果
+type Result struct {
+	Document
+	Score int
+}
+
+// Index は保存したインデックス
+// シャードは検索に必要なものだけを読み込む
+type Index struct {
+	dir    string
+	meta   meta
+	docs   []Document
+	shards map[int]map[string][]int
+}
+
+// インデックスを開く
+func Open(dir string) (*Index, error) {
+	idx := &Index{dir: dir, shards: make(map[int]map[string][]int)}
+	if err := readJSON(dir, "meta", &idx.meta); err != nil {
+		return nil, err
+	}
+	if idx.meta.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported index version %d, rebuild the index", idx.meta.Version)
+	}
+	if err := readJSON(dir, "docs", &idx.docs); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func readJSON(dir, name string, v any) error {
+	b, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return fmt.Errorf("failed to read search index: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to parse search index %s: %w", name, err)
+	}
+	return nil
+}
+
+// 全てのトークンを含む記事を、スコアの高い順に返す
+// limitが0以下の場合は全件を返す
+func (idx *Index) Search(query string, limit int) ([]Result, error) {
+	tokens := queryTokens(query)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var scores map[int]int
+	for _, token := range tokens {
+		postings, err := idx.postings(token)
+		if err != nil {
+			return nil, err
+		}
+		next := make(map[int]int)
+		for i := 0; i+1 < len(postings); i += 2 {
+			doc, score := postings[i], postings[i+1]
+			if scores == nil {
+				next[doc] = score
+			} else if s, ok := scores[doc]; ok {
+				next[doc] = s + score
+			}
+		}
+		scores = next
+		if len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for doc, score := range scores {
+		if doc < 0 || doc >= len(idx.docs) {
+			continue
+		}
+		results = append(results, Result{Document: idx.docs[doc], Score: score})
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(b.Score-a.Score, strings.Compare(b.Created, a.Created), strings.Compare(a.ID, b.ID))
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (idx *Index) postings(token string) ([]int, error) {
+	n := shardOf(token, idx.meta.Shards)
+	shard, ok := idx.shards[n]
+	if !ok {
+		if err := readJSON(idx.dir, shardName(n), &shard); err != nil {
+			return nil, err
+		}
+		idx.shards[n] = shard
+	}
+	return shard[token], nil
+}
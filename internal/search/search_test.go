@@ -0,0 +1,133 @@
+package search
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Go", []string{"g", "go", "o"}},
+		{"設計", []string{"設", "設計", "計"}},
+		// 全角英数字は半角の小文字に揃える
+		{"ＧＯ１", []string{"g", "go", "o", "o1", "1"}},
+		{"GoとAWS", []string{"g", "go", "o", "oと", "と", "とa", "a", "aw", "w", "ws", "s"}},
+		// 記号と空白で区切り、区切りをまたぐ2-gramは作らない
+		{"a-b c", []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTokens(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"Go", []string{"go"}},
+		{"ｇｏ", []string{"go"}},
+		// 1文字の語は1-gram, 2文字以上の語は2-gramで検索し、重複を除いて並べる
+		{"設 設計 計", []string{"設", "設計", "計"}},
+		{"Goの設計", []string{"go", "の設", "設計", "oの"}},
+		{"AWS aws", []string{"aw", "ws"}},
+	}
+	for _, tt := range tests {
+		want := slices.Clone(tt.want)
+		slices.Sort(want)
+		if got := queryTokens(tt.query); !slices.Equal(got, want) {
+			t.Errorf("queryTokens(%q) = %q, want %q", tt.query, got, want)
+		}
+	}
+}
+
+// 検索ページのJavaScriptと同じシャードになるよう、固定の値と比較する
+func TestShardOf(t *testing.T) {
+	tests := []struct {
+		token  string
+		shards int
+		want   int
+	}{
+		{"go", 32, 11},
+		{"go", 7, 2},
+		{"設計", 32, 8},
+		{"a", 32, 12},
+		{"ｇｏ", 32, 27}, // 正規化前のトークンは別のシャード
+		{"go", 1, 0},
+	}
+	for _, tt := range tests {
+		if got := shardOf(tt.token, tt.shards); got != tt.want {
+			t.Errorf("shardOf(%q, %d) = %d, want %d", tt.token, tt.shards, got, tt.want)
+		}
+	}
+}
+
+// エクスポートしたディレクトリからインデックスを作成し、開き直して検索する
+func TestBuildAndSearch(t *testing.T) {
+	exportDir := t.TempDir()
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	articles := []models.Article{
+		{ID: "a", Title: "Goの設計", Body: "本文", CreatedAt: created, User: &models.User{ID: "alice"}, Tags: []models.Tagging{{Name: "Go"}}},
+		{ID: "b", Title: "インフラ", Body: "Goを使う", CreatedAt: created.AddDate(0, 1, 0), User: &models.User{ID: "bob"}, Tags: []models.Tagging{{Name: "AWS"}},
+			Comments: []models.Comment{{Body: "設計の相談", User: models.User{ID: "carol"}}}},
+	}
+	for _, v := range articles {
+		dir := filepath.Join(exportDir, "dev", v.ID)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(v)
+		if err := os.WriteFile(filepath.Join(dir, v.ID+"_metadata.json"), b, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	indexDir := filepath.Join(t.TempDir(), "search")
+	if err := Build(Options{ExportDir: exportDir, IndexDir: indexDir, Shards: 4}); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := Open(indexDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// タイトルとタグの重みが本文より大きい
+		{"go", []string{"a", "b"}},
+		{"ＧＯ", []string{"a", "b"}},
+		{"設計", []string{"a", "b"}},
+		{"carol", []string{"b"}},
+		{"aws インフラ", []string{"b"}},
+		{"存在しない", nil},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(tt.query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package workerpool は上限付きの並行数で処理を実行する
+package workerpool
+
+import (
+	"errors"
+	"sync"
+)
+
+// 0からn-1までのインデックスについて、最大concurrency個のgoroutineでfnを実行し、全ての完了を待つ
+// いずれかがエラーを返した場合は新しい処理の開始をやめ、発生したエラーをインデックス順に結合して返す
+// 結果はfnの中でインデックスを使ってスライスに格納することで、実行順によらず決定的になる
+func Run(n, concurrency int, fn func(i int) error) error {
+	concurrency = max(1, min(concurrency, n))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+		errs   = make([]error, n)
+		next   = 0
+	)
+
+	// 次に処理するインデックスを取得する, エラー発生後は-1を返す
+	take := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed || next >= n {
+			return -1
+		}
+		i := next
+		next++
+		return i
+	}
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := take(); i >= 0; i = take() {
+				if err := fn(i); err != nil {
+					mu.Lock()
+					errs[i] = err
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
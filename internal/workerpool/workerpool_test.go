@@ -0,0 +1,102 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 同時に実行される数はconcurrencyを超えず、全てのインデックスを1回ずつ処理する
+func TestRunConcurrency(t *testing.T) {
+	tests := []struct {
+		n, concurrency int
+		wantMax        int32
+	}{
+		{10, 3, 3},
+		{2, 8, 2},
+		{5, 0, 1},
+		{0, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("n=%d,concurrency=%d", tt.n, tt.concurrency), func(t *testing.T) {
+			var running, peak atomic.Int32
+			done := make([]atomic.Int32, tt.n)
+			err := Run(tt.n, tt.concurrency, func(i int) error {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					p := peak.Load()
+					if cur <= p || peak.CompareAndSwap(p, cur) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				done[i].Add(1)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := peak.Load(); got != tt.wantMax {
+				t.Errorf("peak concurrency = %d, want %d", got, tt.wantMax)
+			}
+			for i := range done {
+				if got := done[i].Load(); got != 1 {
+					t.Errorf("index %d ran %d times", i, got)
+				}
+			}
+		})
+	}
+}
+
+// エラー後は新しい処理を開始せず、エラーをインデックス順に結合して返す
+func TestRunError(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var started atomic.Int32
+	err := Run(100, 2, func(i int) error {
+		started.Add(1)
+		switch i {
+		case 1:
+			time.Sleep(5 * time.Millisecond)
+			return errB
+		case 0:
+			time.Sleep(10 * time.Millisecond)
+			return errA
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("err = %v, want both errors", err)
+	}
+	if err.Error() != "a\nb" {
+		t.Errorf("err = %q, want errors in index order", err.Error())
+	}
+	if got := started.Load(); got >= 100 {
+		t.Errorf("started = %d, want to stop after the error", got)
+	}
+}
+
+// キャンセルされた場合はfnがctx.Err()を返すことで残りの処理を開始しない
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var started atomic.Int32
+	err := Run(100, 4, func(i int) error {
+		started.Add(1)
+		if i == 10 {
+			cancel()
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if got := started.Load(); got >= 100 {
+		t.Errorf("started = %d, want to stop after cancellation", got)
+	}
+}
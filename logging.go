@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// ログの形式
+const (
+	logFormatText = "text" // key=value 形式
+	logFormatJSON = "json" // 1行に1つのJSON (CIでの集計など)
+)
+
+// ログのフラグ, newFlagSetで全てのコマンドに追加する
+func addLogFlags(fs *flag.FlagSet) {
+	fs.String("log_level", "info", "ログのレベル (debug, info, warn, error)")
+	fs.String("log_format", logFormatText, "ログの形式 (text, json)")
+}
+
+// -log_level, -log_format に従ってslogのデフォルトのロガーを設定する
+// ログは標準エラー出力に書き込み、標準出力にはコマンドの結果 (検索結果, レポートなど) のみを書き込む
+func setupLogger(flags *flag.FlagSet) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(flags.Lookup("log_level").Value.String())); err != nil {
+		return fmt.Errorf("invalid -log_level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format := flags.Lookup("log_format").Value.String(); format {
+	case logFormatText:
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("log_format must be %q or %q", logFormatText, logFormatJSON)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -1,170 +1,84 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/qiita_export/models"
-	"github.com/qiita_export/repository"
 )
 
-const (
-	retryTimes = 5
-)
-
-var config *models.Config
+// 出力ディレクトリのデフォルト値, 全てのコマンドで -dir で変更できる
+const defaultOutputDir = "output"
 
-func main() {
-	outputDir := flag.String("dir", "output", "default value is 'output'")
-	page := flag.Int("page", 1, "default value is 1")
-	perPage := flag.Int("per_page", 100, "default value is 100")
-	query := flag.String("query", "", "default value is empty")
-	flag.Parse()
-
-	// 時間計測用
-	start := time.Now()
+// サブコマンド
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	// .envを環境変数にセットする
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
+var commands = []command{
+	{"export", "記事, コメント, 絵文字リアクション, アセットをエクスポートする", func(args []string) error { return runExport(args, false) }},
+	{"sync", "変更のあった記事のみエクスポートする (export -sync と同じ)", func(args []string) error { return runExport(args, true) }},
+	{"add-metadata", "Markdownの末尾に記事IDとメタデータへのリンクを追加する", runAddMetadata},
+	{"replace-refs", "Qiitaの記事へのリンクを、エクスポートしたMarkdownへの相対パスに置換する", runReplaceRefs},
+	{"replace-links", "CSVの対応表に従ってMarkdownのURLを置換する", runReplaceLinks},
+	{"archive", "記事のディレクトリをチャンクに分けてzipにする", runArchive},
+	{"site", "サーバーなしで閲覧できる静的なHTMLのサイトを生成する", runSite},
+	{"index", "全文検索のインデックスを作成する", runIndex},
+	{"search", "全文検索のインデックスを検索する", runSearch},
+	{"config", "設定ファイルを検証する (config validate)", runConfig},
+	{"verify", "出力ディレクトリの記事のファイルが揃っているか確認する", runVerify},
+}
 
-	// 設定
-	config = models.NewConfig()
-	if config.AccessToken == "" || config.Domain == "" {
-		log.Fatalf("config required")
+func main() {
+	// サブコマンドを省略した場合 (go run . -dir output など) はexportとして扱う
+	name, args := "export", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-
-	// 処理
-	if err := execute(config, *outputDir, *page, *perPage, *query); err != nil {
-		log.Fatalf("Error execute: %v", err)
+	if name == "help" {
+		printUsage()
+		return
 	}
 
-	fmt.Printf("実行時間: %f min, リクエスト数:%d", time.Since(start).Minutes(), repository.RequestCount)
-}
-
-func execute(config *models.Config, outputDir string, page, perPage int, query string) error {
-	api := repository.NewQiitaAPI(config.Domain, config.AccessToken)
-
-	for {
-		params := fmt.Sprintf("page=%d&per_page=%d&query=%s", page, perPage, query)
-
-		var articles []models.Article
-		var requestErr error
-		var total int
-		// リトライ処理
-		for range retryTimes {
-			var err error
-			articles, total, err = api.RequestArticles(params)
-			if err != nil {
-				requestErr = errors.Join(fmt.Errorf("failed to request page=%d, per_page=%d: %w", page, perPage, err))
-				fmt.Printf("retry page=%d, error:%v\n", page, err)
-				time.Sleep(5 * time.Second)
-			} else {
-				break
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(args); err != nil {
+				slog.Error("コマンドが失敗しました", "command", name, "error", err)
+				os.Exit(1)
 			}
+			return
 		}
-
-		if total <= 0 && requestErr != nil {
-			return requestErr
-		}
-
-		// outputディレクトリの作成
-		if err := os.MkdirAll(outputDir, 0777); err != nil {
-			return err
-		}
-
-		// コメント, 絵文字の取得
-		for _, v := range articles {
-			comments, err := api.RequestComments(v.ID)
-			if err != nil {
-				return fmt.Errorf("コメントの取得に失敗しました: %w", err)
-			}
-			reactions, err := api.RequestArticleReactions(v.ID)
-			if err != nil {
-				return fmt.Errorf("絵文字リアクションの取得に失敗しました: %w", err)
-			}
-			v.Comments = comments
-			v.EmojiReactions = reactions
-
-			// mkdir
-			groupDir := filepath.Join(outputDir, v.Group.Name)
-			artDir := filepath.Join(groupDir, v.ID) // 記事名にSlashがある場合にエラーになるため、IDを採用
-			if err := os.MkdirAll(artDir, 0777); err != nil {
-				return err
-			}
-
-			if err := downloadArticleToLocal(&v, artDir); err != nil {
-				return fmt.Errorf("記事のダウンロードに失敗しました: %w", err)
-			}
-
-			if err := api.DownloadArticleAssets(v.Body, artDir); err != nil {
-				return fmt.Errorf("記事のアセットのダウンロードに失敗しました: %w", err)
-			}
-		}
-
-		if page*perPage > total {
-			break
-		}
-
-		// 進捗状況
-		completed := min(page*perPage, total)
-		progress := float64(completed) * 100 / float64(total)
-		remainingPages := (max(0, total-page*perPage) + perPage - 1) / perPage
-
-		fmt.Printf("Progress: %.1f%% (page=%d, remaining=%d)\n", progress, page, remainingPages)
-		page++
-
-		// API制限を考慮して、リクエスト間隔を空ける
-		time.Sleep(10 * time.Millisecond)
 	}
 
-	return nil
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+	printUsage()
+	os.Exit(2)
 }
 
-func downloadArticleToLocal(art *models.Article, artDir string) error {
-	fmt.Println(art.Title, strings.Repeat("=", 20))
-
-	// ファイル名のサニタイズ
-	sanitizedTitle := sanitizeFilename(art.Title)
-
-	// メタデータの保存
-	metadataPath := filepath.Join(artDir, sanitizedTitle+"_metadata.json")
-	metadataJSON, err := json.MarshalIndent(art, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-	if err := os.WriteFile(metadataPath, metadataJSON, 0666); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: qiita-export <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.summary)
 	}
-	fmt.Println("メタデータの保存に成功しました")
-
-	// Markdownファイルの保存
-	mdPath := filepath.Join(artDir, sanitizedTitle+".md")
-	if err := os.WriteFile(mdPath, []byte(art.Body), 0666); err != nil {
-		return fmt.Errorf("failed to write markdown: %w", err)
-	}
-	fmt.Println("コンテンツの保存に成功しました")
+	fmt.Fprintln(os.Stderr, "\n各コマンドのフラグは qiita-export <command> -h で確認できる")
+}
 
-	return nil
+// サブコマンドのフラグ
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("qiita-export "+name, flag.ExitOnError)
+	fs.String("config", "", "設定ファイル (.yaml, .toml), 未指定の場合は $"+models.EnvConfigFileKey+" またはカレントディレクトリの qiita-export.yaml")
+	fs.String("profile", "", "設定ファイルのプロファイル, 未指定の場合は $"+models.EnvProfileKey+" または default_profile")
+	addLogFlags(fs)
+	return fs
 }
 
-// ファイル名として使用できない文字をサニタイズする関数
-func sanitizeFilename(filename string) string {
-	// Windowsでも使用できるよう、一般的な禁止文字をすべて置換
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := filename
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
-	}
-	return result
+// 出力ディレクトリ (<group>/<id>/ に記事を保存するディレクトリ) のフラグ
+func addDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("dir", defaultOutputDir, "出力ディレクトリ")
 }
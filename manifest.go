@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/qiita_export/repository"
+)
+
+// エクスポートした記事とアセットの一覧を保存するファイル名
+const manifestFileName = "manifest.json"
+
+// マニフェストの形式のバージョン, 形式を変更した場合は上げる
+const manifestVersion = 1
+
+// エクスポートの結果の一覧
+// verifyコマンドで、出力ディレクトリのファイルの欠落, 追加, 切り詰め, 変更を検出するために使う
+type exportManifest struct {
+	Version     int               `json:"version"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Query       string            `json:"query"`
+	TotalCount  int               `json:"total_count"` // APIのTotal-Countヘッダーの値, 取得していない場合は-1
+	Articles    []manifestArticle `json:"articles"`
+}
+
+type manifestArticle struct {
+	ID                    string          `json:"id"`
+	Title                 string          `json:"title"`
+	Dir                   string          `json:"dir"` // 出力ディレクトリからの相対パス
+	UpdatedAt             time.Time       `json:"updated_at"`
+	CommentsCount         int             `json:"comments_count"`
+	ReactionsCount        int             `json:"reactions_count"`         // 記事の絵文字リアクション
+	CommentReactionsCount int             `json:"comment_reactions_count"` // コメントの絵文字リアクション
+	Files                 []manifestFile  `json:"files"`                   // 記事のディレクトリのファイル (アセットを含む)
+	Assets                []manifestAsset `json:"assets"`
+}
+
+// パスは出力ディレクトリからの相対パス (スラッシュ区切り)
+type manifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type manifestAsset struct {
+	URL string `json:"url"`
+	manifestFile
+}
+
+// エクスポート中に、マニフェストを作り直す記事とTotal-Countを記録する
+// 記事を並行して処理するため、muで保護する
+type manifestBuilder struct {
+	mu         sync.Mutex
+	query      string
+	totalCount int
+	exported   map[string]bool
+}
+
+func newManifestBuilder(query string) *manifestBuilder {
+	return &manifestBuilder{query: query, totalCount: -1, exported: make(map[string]bool)}
+}
+
+func (b *manifestBuilder) setTotalCount(total int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.totalCount = total
+}
+
+// この実行で保存した記事として記録する
+func (b *manifestBuilder) markExported(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exported[id] = true
+}
+
+// 出力ディレクトリの記事からマニフェストを作成して保存する
+// この実行で保存していない記事は、前回のマニフェストの内容を引き継ぐ (前回のエクスポート後の変更を検出できるように)
+// 前回のマニフェストにない記事は、現在のファイルから作成する
+func (b *manifestBuilder) save(outputDir string, api *repository.QiitaAPI, store *repository.AssetStore) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, err := loadManifest(outputDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("前回のマニフェストを読み込めないため、作り直します", "error", err)
+	}
+	prevArticles := make(map[string]manifestArticle)
+	if previous != nil {
+		for _, a := range previous.Articles {
+			prevArticles[a.ID] = a
+		}
+	}
+
+	m := exportManifest{
+		Version:     manifestVersion,
+		GeneratedAt: time.Now(),
+		Query:       b.query,
+		TotalCount:  b.totalCount,
+		Articles:    []manifestArticle{},
+	}
+	// 再開や同期で一覧を取得しなかった場合は、前回の値を引き継ぐ
+	if m.TotalCount < 0 && previous != nil && previous.Query == b.query {
+		m.TotalCount = previous.TotalCount
+	}
+
+	exported, err := repository.LoadExportedArticles(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to load exported articles: %w", err)
+	}
+	for _, e := range exported {
+		if prev, ok := prevArticles[e.Article.ID]; ok && !b.exported[e.Article.ID] {
+			m.Articles = append(m.Articles, prev)
+			continue
+		}
+		a, err := newManifestArticle(outputDir, e, api, store)
+		if err != nil {
+			return fmt.Errorf("failed to create manifest of %s: %w", e.Dir, err)
+		}
+		m.Articles = append(m.Articles, a)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(outputDir, manifestFileName), data, 0666); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// 記事のディレクトリのファイルと、メタデータの本文から抽出したアセットのファイルを記録する
+func newManifestArticle(outputDir string, e repository.ExportedArticle, api *repository.QiitaAPI, store *repository.AssetStore) (manifestArticle, error) {
+	v := e.Article
+	a := manifestArticle{
+		ID:             v.ID,
+		Title:          v.Title,
+		UpdatedAt:      v.UpdatedAt,
+		CommentsCount:  len(v.Comments),
+		ReactionsCount: len(v.EmojiReactions),
+		Files:          []manifestFile{},
+		Assets:         []manifestAsset{},
+	}
+	for _, c := range v.Comments {
+		a.CommentReactionsCount += len(c.EmojiReactions)
+	}
+	dir, err := relSlash(outputDir, e.Dir)
+	if err != nil {
+		return manifestArticle{}, err
+	}
+	a.Dir = dir
+
+	entries, err := os.ReadDir(e.Dir)
+	if err != nil {
+		return manifestArticle{}, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		f, err := newManifestFile(outputDir, filepath.Join(e.Dir, entry.Name()))
+		if err != nil {
+			return manifestArticle{}, err
+		}
+		a.Files = append(a.Files, f)
+	}
+
+	// メタデータのbodyはAPIから取得した内容のまま (rendered_bodyは -rewrite_rendered_body で置換される場合がある)
+	for _, u := range api.ExtractAssetURLs(v.Body, v.RenderedBody) {
+		p, ok := repository.FindAssetFile(e.Dir, u)
+		if !ok && store != nil {
+			var entry repository.AssetEntry
+			if entry, ok = store.Lookup(u); ok {
+				p = store.FilePath(entry)
+			}
+		}
+		if !ok {
+			continue // ダウンロードに失敗したアセットは asset_report.json に記録される
+		}
+		f, err := newManifestFile(outputDir, p)
+		if err != nil {
+			return manifestArticle{}, err
+		}
+		a.Assets = append(a.Assets, manifestAsset{URL: u, manifestFile: f})
+	}
+
+	return a, nil
+}
+
+func newManifestFile(outputDir, path string) (manifestFile, error) {
+	rel, err := relSlash(outputDir, path)
+	if err != nil {
+		return manifestFile{}, err
+	}
+	size, sum, err := hashFile(path)
+	if err != nil {
+		return manifestFile{}, err
+	}
+	return manifestFile{Path: rel, Size: size, SHA256: sum}, nil
+}
+
+// ファイルのサイズとSHA-256
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 出力ディレクトリからのスラッシュ区切りの相対パス
+func relSlash(base, path string) (string, error) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func loadManifest(outputDir string) (*exportManifest, error) {
+	b, err := os.ReadFile(filepath.Join(outputDir, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	var m exportManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestFileName, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
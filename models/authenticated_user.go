@@ -0,0 +1,9 @@
+package models
+
+// アクセストークンに紐づくユーザー
+// https://qiita.com/api/v2/docs#%E8%AA%8D%E8%A8%BC%E4%B8%AD%E3%81%AE%E3%83%A6%E3%83%BC%E3%82%B6
+type AuthenticatedUser struct {
+	User
+	ImageMonthlyUploadLimit     int `json:"image_monthly_upload_limit"`
+	ImageMonthlyUploadRemaining int `json:"image_monthly_upload_remaining"`
+}
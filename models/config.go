@@ -1,16 +1,25 @@
 package models
 
-import "os"
+import (
+	"os"
+	"strings"
+)
 
 const (
 	envAccessTokenKey = "ACCESS_TOKEN"
 	envDomainKey      = "DOMAIN"
 	outputDir         = "output"
+
+	// アセットとして扱うホスト, 扱わないホスト (カンマ区切り)
+	envAssetAllowHostsKey = "ASSET_ALLOW_HOSTS"
+	envAssetDenyHostsKey  = "ASSET_DENY_HOSTS"
 )
 
 type Config struct {
-	AccessToken string
-	Domain      string
+	AccessToken     string
+	Domain          string
+	AssetAllowHosts []string
+	AssetDenyHosts  []string
 }
 
 func NewConfig() *Config {
@@ -18,7 +27,56 @@ func NewConfig() *Config {
 	domain := os.Getenv(envDomainKey)
 
 	return &Config{
-		AccessToken: accessToken,
-		Domain:      domain,
+		AccessToken:     accessToken,
+		Domain:          domain,
+		AssetAllowHosts: splitList(os.Getenv(envAssetAllowHostsKey)),
+		AssetDenyHosts:  splitList(os.Getenv(envAssetDenyHostsKey)),
+	}
+}
+
+// カンマ区切りの値を分割する, 空の要素は除く
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// 環境変数で指定されていない値をプロファイルの値で補う (環境変数を優先する)
+// 上書きした環境変数の名前を返す
+// token_commandの実行は呼び出し側で行う
+func (c *Config) ApplyProfile(p *Profile) (overridden []string) {
+	fill := func(key string, dst *string, v string) {
+		if v == "" {
+			return
+		}
+		if *dst != "" {
+			if *dst != v {
+				overridden = append(overridden, key)
+			}
+			return
+		}
+		*dst = v
+	}
+	fill(envAccessTokenKey, &c.AccessToken, p.Token)
+	fill(envDomainKey, &c.Domain, p.Domain)
+
+	if len(p.AssetAllowHosts) > 0 {
+		if len(c.AssetAllowHosts) > 0 {
+			overridden = append(overridden, envAssetAllowHostsKey)
+		} else {
+			c.AssetAllowHosts = p.AssetAllowHosts
+		}
+	}
+	if len(p.AssetDenyHosts) > 0 {
+		if len(c.AssetDenyHosts) > 0 {
+			overridden = append(overridden, envAssetDenyHostsKey)
+		} else {
+			c.AssetDenyHosts = p.AssetDenyHosts
+		}
 	}
+	return overridden
 }
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定ファイルのデフォルトのパス (カレントディレクトリ), 存在する場合のみ読み込む
+var DefaultConfigFiles = []string{"qiita-export.yaml", "qiita-export.yml", "qiita-export.toml"}
+
+// 設定ファイルのパス, プロファイル名を指定する環境変数
+const (
+	EnvConfigFileKey = "QIITA_EXPORT_CONFIG"
+	EnvProfileKey    = "QIITA_EXPORT_PROFILE"
+)
+
+// ConfigFile は名前付きのプロファイルを持つ設定ファイル (YAML, TOML)
+//
+//	default_profile: team-a
+//	profiles:
+//	  team-a:
+//	    domain: team-a.qiita.com
+//	    token_command: op read op://qiita/team-a/token
+//	    dir: output/team-a
+type ConfigFile struct {
+	DefaultProfile string             `yaml:"default_profile" toml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles" toml:"profiles"`
+}
+
+// Profile はチーム (またはqiita.com) ごとの設定
+// 空の値は未指定として扱い、環境変数やフラグのデフォルト値を使う
+type Profile struct {
+	Domain       string `yaml:"domain" toml:"domain"`
+	Token        string `yaml:"token" toml:"token"`
+	TokenCommand string `yaml:"token_command" toml:"token_command"` // 標準出力をアクセストークンとする (パスワードマネージャーなど)
+	BaseURL      string `yaml:"base_url" toml:"base_url"`
+
+	Dir string `yaml:"dir" toml:"dir"`
+
+	AssetAllowHosts []string `yaml:"asset_allow_hosts" toml:"asset_allow_hosts"`
+	AssetDenyHosts  []string `yaml:"asset_deny_hosts" toml:"asset_deny_hosts"`
+	AssetStore      string   `yaml:"asset_store" toml:"asset_store"`
+	AssetStoreMode  string   `yaml:"asset_store_mode" toml:"asset_store_mode"`
+
+	Query   string        `yaml:"query" toml:"query"`
+	Filters ProfileFilter `yaml:"filters" toml:"filters"`
+	Team    bool          `yaml:"team" toml:"team"` // チーム単位のリソースも保存する
+
+	Format      string `yaml:"format" toml:"format"`
+	FrontMatter string `yaml:"front_matter" toml:"front_matter"`
+	SiteDir     string `yaml:"site_dir" toml:"site_dir"`
+}
+
+// ProfileFilter はエクスポートする記事の絞り込み条件
+// リストはいずれかに一致する記事, 日付は YYYY-MM-DD
+type ProfileFilter struct {
+	Groups       []string `yaml:"groups" toml:"groups"` // グループのURL名
+	Tags         []string `yaml:"tags" toml:"tags"`
+	Users        []string `yaml:"users" toml:"users"`
+	CreatedSince string   `yaml:"created_since" toml:"created_since"`
+	CreatedUntil string   `yaml:"created_until" toml:"created_until"`
+	UpdatedSince string   `yaml:"updated_since" toml:"updated_since"`
+	UpdatedUntil string   `yaml:"updated_until" toml:"updated_until"`
+	IncludeIDs   string   `yaml:"include_ids" toml:"include_ids"` // 記事IDの一覧のファイル
+	ExcludeIDs   string   `yaml:"exclude_ids" toml:"exclude_ids"`
+}
+
+// 設定ファイルを読み込む, 形式は拡張子 (.yaml, .yml, .toml) で判定する
+// 未知のキーはタイプミスの可能性が高いためエラーにする
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var f ConfigFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(b), &f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("failed to parse config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (.yaml, .yml, .toml)", ext)
+	}
+
+	if f.DefaultProfile != "" {
+		if _, ok := f.Profiles[f.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("default_profile %q is not defined in %s", f.DefaultProfile, path)
+		}
+	}
+	return &f, nil
+}
+
+// 名前のプロファイルを返す
+// 名前が空の場合は default_profile, default_profileもない場合はプロファイルが1件のみならそのプロファイルを返す
+// 使用するプロファイルがない場合は空の名前とnilを返す
+func (f *ConfigFile) Profile(name string) (string, *Profile, error) {
+	if name == "" {
+		name = f.DefaultProfile
+	}
+	if name == "" && len(f.Profiles) == 1 {
+		for n := range f.Profiles {
+			name = n
+		}
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return "", nil, fmt.Errorf("profile %q is not defined (%s)", name, strings.Join(f.ProfileNames(), ", "))
+	}
+	return name, &p, nil
+}
+
+// プロファイル名の一覧 (昇順)
+func (f *ConfigFile) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for n := range f.Profiles {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// フラグと同じ名前の設定項目, 値が空の項目は含まない
+// フラグで明示的に指定されていない場合にフラグの値として使う
+func (p *Profile) FlagValues() map[string]string {
+	values := map[string]string{
+		"dir":              p.Dir,
+		"base_url":         p.BaseURL,
+		"query":            p.Query,
+		"asset_store":      p.AssetStore,
+		"asset_store_mode": p.AssetStoreMode,
+		"format":           p.Format,
+		"front_matter":     p.FrontMatter,
+		"site_dir":         p.SiteDir,
+		"group":            strings.Join(p.Filters.Groups, ","),
+		"tag":              strings.Join(p.Filters.Tags, ","),
+		"user":             strings.Join(p.Filters.Users, ","),
+		"created_since":    p.Filters.CreatedSince,
+		"created_until":    p.Filters.CreatedUntil,
+		"updated_since":    p.Filters.UpdatedSince,
+		"updated_until":    p.Filters.UpdatedUntil,
+		"include_ids":      p.Filters.IncludeIDs,
+		"exclude_ids":      p.Filters.ExcludeIDs,
+	}
+	if p.Team {
+		values["team"] = "true"
+	}
+	for k, v := range values {
+		if v == "" {
+			delete(values, k)
+		}
+	}
+	return values
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testConfigYAML = `default_profile: team-a
+profiles:
+  team-a:
+    domain: team-a.qiita.com
+    token_command: echo token
+    dir: output/team-a
+    filters:
+      groups: [dev, ops]
+      created_since: "2024-01-01"
+  public:
+    domain: qiita.com
+    team: true
+`
+
+const testConfigTOML = `default_profile = "team-a"
+
+[profiles.team-a]
+domain = "team-a.qiita.com"
+token_command = "echo token"
+dir = "output/team-a"
+
+[profiles.team-a.filters]
+groups = ["dev", "ops"]
+created_since = "2024-01-01"
+
+[profiles.public]
+domain = "qiita.com"
+team = true
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	for _, tt := range []struct{ name, content string }{
+		{"qiita-export.yaml", testConfigYAML},
+		{"qiita-export.toml", testConfigTOML},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := LoadConfigFile(writeConfigFile(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(f.ProfileNames(), ","); got != "public,team-a" {
+				t.Errorf("profiles = %s", got)
+			}
+			p := f.Profiles["team-a"]
+			if p.Domain != "team-a.qiita.com" || p.TokenCommand != "echo token" || p.Dir != "output/team-a" ||
+				strings.Join(p.Filters.Groups, ",") != "dev,ops" || p.Filters.CreatedSince != "2024-01-01" {
+				t.Errorf("team-a = %+v", p)
+			}
+			if !f.Profiles["public"].Team {
+				t.Error("public.team is not set")
+			}
+		})
+	}
+}
+
+// 未知のキー, 未定義の default_profile, 未対応の拡張子はエラーにする
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content, wantErr string
+	}{
+		{"YAMLの未知のキー", "c.yaml", "profiles:\n  a:\n    domian: example.qiita.com\n", "domian"},
+		{"YAMLの絞り込みの未知のキー", "c.yml", "profiles:\n  a:\n    filters:\n      group: [dev]\n", "group"},
+		{"YAMLのトップレベルの未知のキー", "c.yaml", "profile:\n  a: {}\n", "profile"},
+		{"TOMLの未知のキー", "c.toml", "[profiles.a]\ndomian = \"example.qiita.com\"\n", "domian"},
+		{"TOMLの絞り込みの未知のキー", "c.toml", "[profiles.a.filters]\ngroup = [\"dev\"]\n", "group"},
+		{"未定義のdefault_profile", "c.yaml", "default_profile: b\nprofiles:\n  a: {}\n", `default_profile "b"`},
+		{"未対応の拡張子", "c.json", "{}", "unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFile(writeConfigFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfigFile() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 空のファイルはプロファイルのない設定として読み込む
+func TestLoadConfigFileEmpty(t *testing.T) {
+	f, err := LoadConfigFile(writeConfigFile(t, "c.yaml", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, p, err := f.Profile(""); name != "" || p != nil || err != nil {
+		t.Errorf("Profile() = %q, %v, %v", name, p, err)
+	}
+}
+
+func TestConfigFileProfile(t *testing.T) {
+	two := &ConfigFile{Profiles: map[string]Profile{"a": {Domain: "a.qiita.com"}, "b": {Domain: "b.qiita.com"}}}
+	tests := []struct {
+		name     string
+		file     *ConfigFile
+		profile  string
+		wantName string
+		wantErr  bool
+	}{
+		{"名前を指定", two, "b", "b", false},
+		{"default_profile", &ConfigFile{DefaultProfile: "a", Profiles: two.Profiles}, "", "a", false},
+		{"名前の指定がdefault_profileより優先", &ConfigFile{DefaultProfile: "a", Profiles: two.Profiles}, "b", "b", false},
+		{"プロファイルが1件のみ", &ConfigFile{Profiles: map[string]Profile{"only": {}}}, "", "only", false},
+		{"複数のプロファイルから選択しない", two, "", "", false},
+		{"未定義のプロファイル", two, "c", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, p, err := tt.file.Profile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Profile() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name != tt.wantName || (p == nil) != (tt.wantName == "") {
+				t.Errorf("Profile() = %q, %v, want %q", name, p, tt.wantName)
+			}
+		})
+	}
+}
+
+// フラグの名前で値を返し、空の値は含めない
+func TestFlagValues(t *testing.T) {
+	p := &Profile{
+		Domain:         "team-a.qiita.com", // フラグではない項目は含めない
+		Dir:            "output/team-a",
+		AssetStoreMode: "ref",
+		Format:         "hugo",
+		Team:           true,
+		Filters: ProfileFilter{
+			Groups:       []string{"dev", "ops"},
+			Tags:         []string{"Go"},
+			UpdatedUntil: "2024-12-31",
+			ExcludeIDs:   "exclude.txt",
+		},
+	}
+	want := map[string]string{
+		"dir":              "output/team-a",
+		"asset_store_mode": "ref",
+		"format":           "hugo",
+		"team":             "true",
+		"group":            "dev,ops",
+		"tag":              "Go",
+		"updated_until":    "2024-12-31",
+		"exclude_ids":      "exclude.txt",
+	}
+	if got := p.FlagValues(); !maps.Equal(got, want) {
+		t.Errorf("FlagValues() = %v, want %v", got, want)
+	}
+	if got := (&Profile{}).FlagValues(); len(got) > 0 {
+		t.Errorf("FlagValues() of an empty profile = %v", got)
+	}
+}
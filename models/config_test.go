@@ -0,0 +1,74 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+// 環境変数の値を優先し、環境変数で指定されていない値のみプロファイルで補う
+func TestApplyProfile(t *testing.T) {
+	profile := &Profile{
+		Domain:          "team-a.qiita.com",
+		Token:           "profile-token",
+		AssetAllowHosts: []string{"cdn.example.com"},
+		AssetDenyHosts:  []string{"team-a.qiita.com/files/large/"},
+	}
+	tests := []struct {
+		name           string
+		env            Config
+		want           Config
+		wantOverridden []string
+	}{
+		{
+			name: "環境変数なし",
+			want: Config{
+				AccessToken:     "profile-token",
+				Domain:          "team-a.qiita.com",
+				AssetAllowHosts: []string{"cdn.example.com"},
+				AssetDenyHosts:  []string{"team-a.qiita.com/files/large/"},
+			},
+		},
+		{
+			name: "環境変数がプロファイルと異なる",
+			env:  Config{AccessToken: "env-token", Domain: "team-b.qiita.com", AssetAllowHosts: []string{"other.example.com"}},
+			want: Config{
+				AccessToken:     "env-token",
+				Domain:          "team-b.qiita.com",
+				AssetAllowHosts: []string{"other.example.com"},
+				AssetDenyHosts:  []string{"team-a.qiita.com/files/large/"},
+			},
+			wantOverridden: []string{envAccessTokenKey, envDomainKey, envAssetAllowHostsKey},
+		},
+		{
+			name: "環境変数がプロファイルと同じ",
+			env:  Config{AccessToken: "profile-token", Domain: "team-a.qiita.com"},
+			want: Config{
+				AccessToken:     "profile-token",
+				Domain:          "team-a.qiita.com",
+				AssetAllowHosts: []string{"cdn.example.com"},
+				AssetDenyHosts:  []string{"team-a.qiita.com/files/large/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.env
+			overridden := c.ApplyProfile(profile)
+			if !slices.Equal(overridden, tt.wantOverridden) {
+				t.Errorf("overridden = %v, want %v", overridden, tt.wantOverridden)
+			}
+			if c.AccessToken != tt.want.AccessToken || c.Domain != tt.want.Domain ||
+				!slices.Equal(c.AssetAllowHosts, tt.want.AssetAllowHosts) || !slices.Equal(c.AssetDenyHosts, tt.want.AssetDenyHosts) {
+				t.Errorf("config = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+// 空の値のプロファイルは何も変更しない
+func TestApplyEmptyProfile(t *testing.T) {
+	c := Config{AccessToken: "env-token"}
+	if overridden := c.ApplyProfile(&Profile{}); len(overridden) > 0 || c.AccessToken != "env-token" || c.Domain != "" {
+		t.Errorf("config = %+v, overridden = %v", c, overridden)
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+// Qiita Teamのグループのメンバー
+// https://qiita.com/api/v2/docs#%E3%82%B0%E3%83%AB%E3%83%BC%E3%83%97%E3%83%A1%E3%83%B3%E3%83%90%E3%83%BC
+type GroupMember struct {
+	Description string `json:"description"`
+	Email       string `json:"email"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Qiita Teamのプロジェクト
+// https://qiita.com/api/v2/docs#%E3%83%97%E3%83%AD%E3%82%B8%E3%82%A7%E3%82%AF%E3%83%88
+type Project struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Body           string    `json:"body"`
+	RenderedBody   string    `json:"rendered_body"`
+	Archived       bool      `json:"archived"`
+	ReactionsCount int       `json:"reactions_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Comments       []Comment `json:"comments"` // コメント, 同一エンドポイントでは取得できない
+}
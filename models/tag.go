@@ -0,0 +1,16 @@
+package models
+
+// Qiita Teamのタグ
+// https://qiita.com/api/v2/docs#%E3%82%BF%E3%82%B0
+type Tag struct {
+	FollowersCount int     `json:"followers_count"`
+	IconURL        *string `json:"icon_url"`
+	ID             string  `json:"id"`
+	ItemsCount     int     `json:"items_count"`
+}
+
+// 記事やテンプレートに付けられたタグ (タグ付け)
+type Tagging struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
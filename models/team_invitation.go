@@ -0,0 +1,8 @@
+package models
+
+// Qiita Teamへの招待中のメンバー
+// https://qiita.com/api/v2/docs#%E6%8B%9B%E5%BE%85%E4%B8%AD%E3%81%AE%E3%83%A1%E3%83%B3%E3%83%90%E3%83%BC
+type TeamInvitation struct {
+	Email string `json:"email"`
+	URL   string `json:"url"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Qiita Teamのテンプレート
+// expanded_* は %{Year} などの変数を展開した値
+// https://qiita.com/api/v2/docs#%E3%83%86%E3%83%B3%E3%83%97%E3%83%AC%E3%83%BC%E3%83%88
+type Template struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	Tags          []Tagging `json:"tags"`
+	ExpandedTitle string    `json:"expanded_title"`
+	ExpandedBody  string    `json:"expanded_body"`
+	ExpandedTags  []Tagging `json:"expanded_tags"`
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// dry-runで作成するエクスポートの計画
+// 記事の一覧のみ取得し、一覧に含まれるコメント数, 絵文字リアクション数, 本文のアセットから見積もる
+type exportPlan struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Query       string       `json:"query"`
+	TotalCount  int          `json:"total_count"` // APIのTotal-Countヘッダーの値
+	Listed      int          `json:"listed"`      // 一覧で取得した記事数
+	Articles    int          `json:"articles"`    // 保存する記事数
+	Skipped     int          `json:"skipped"`     // 絞り込みの条件, 同期で変更がないためスキップする記事数
+	Groups      []planGroup  `json:"groups"`
+	Requests    planRequests `json:"requests"`
+	// レート制限の状態, ヘッダーを受け取っていない場合はnil
+	RateLimit *repository.RateLimit `json:"rate_limit"`
+	// 見積もりの所要時間 (秒), 一覧の取得にかかった時間から計算する
+	EstimatedSeconds float64   `json:"estimated_seconds"`
+	Files            planFiles `json:"files"`
+}
+
+// グループごとの保存する記事の件数
+type planGroup struct {
+	Name      string `json:"name"`
+	Articles  int    `json:"articles"`
+	Comments  int    `json:"comments"`
+	Reactions int    `json:"reactions"`
+	Assets    int    `json:"assets"`
+}
+
+// 見積もりのリクエスト数
+// アセットのダウンロードはAPIの利用制限の対象外のため別に数える
+type planRequests struct {
+	Listing          int `json:"listing"`           // 記事の一覧 (取得済み)
+	Comments         int `json:"comments"`          // コメントの一覧
+	CommentReactions int `json:"comment_reactions"` // コメントごとの絵文字リアクション
+	ArticleReactions int `json:"article_reactions"` // 記事の絵文字リアクション
+	API              int `json:"api"`               // APIのリクエスト数の合計
+	Assets           int `json:"assets"`            // ダウンロードするアセット
+}
+
+// 作成, 上書き, 削除するファイルのパス
+type planFiles struct {
+	Create    []string `json:"create"`
+	Overwrite []string `json:"overwrite"`
+	Delete    []string `json:"delete"`
+}
+
+// 記事の一覧のみ取得して計画を作成し、ログに出力する (計画は実行結果のレポートにも含める)
+// 出力ディレクトリには何も書き込まない (-plan を指定した場合はそのファイルのみ保存する)
+func planExport(ctx context.Context, api *repository.QiitaAPI, opts exportOptions) error {
+	if opts.resume {
+		slog.Warn("dry-runではジャーナルの完了済みの記事を考慮しません")
+	}
+
+	// 出力先には何も作成しないため、アセットストアはインデックスの読み込みのみ行う
+	site, err := newSiteLayout(opts.siteFormat, opts.frontMatter, opts.siteDir)
+	if err != nil {
+		return err
+	}
+	opts.site = site
+	if opts.assetStoreDir != "" {
+		if opts.assetStore, err = repository.LoadAssetStore(opts.assetStoreDir); err != nil {
+			return fmt.Errorf("failed to open asset store: %w", err)
+		}
+	}
+
+	var locals map[string]localArticle
+	report := newSyncReport()
+	if opts.sync {
+		locals, err = loadLocalArticles(opts.outputDir)
+		if err != nil {
+			return fmt.Errorf("保存済みの記事の読み込みに失敗しました: %w", err)
+		}
+	}
+
+	plan := &exportPlan{
+		GeneratedAt: time.Now(),
+		Query:       opts.query,
+		TotalCount:  -1,
+		Files:       planFiles{Create: []string{}, Overwrite: []string{}, Delete: []string{}},
+	}
+	groups := make(map[string]*planGroup)
+	var elapsed time.Duration
+
+	pager, err := api.ArticlePages(opts.page, opts.perPage, opts.query)
+	if err != nil {
+		return err
+	}
+	for !pager.Done() {
+		begin := time.Now()
+		result, err := pager.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to request page=%d, per_page=%d: %w", pager.Page(), opts.perPage, err)
+		}
+		elapsed += time.Since(begin)
+		plan.Requests.Listing++
+		plan.TotalCount = result.Total
+		plan.Listed += len(result.Items)
+
+		for i := range result.Items {
+			v := &result.Items[i]
+			if !opts.filter.match(v) || (opts.sync && !report.needsExport(v, locals)) {
+				plan.Skipped++
+				continue
+			}
+			plan.addArticle(api, v, groups, opts, locals)
+		}
+
+		if !pager.Done() {
+			slog.Info("一覧を取得しました", "page", result.Number, "completed", min(result.Number*opts.perPage, result.Total), "total", result.Total)
+		}
+	}
+
+	// 一覧を全て取得した場合のみ、削除された記事を判定できる (execute と同じ条件)
+	if opts.sync && opts.prune {
+		if opts.query != "" || opts.filter.active() || opts.page != 1 {
+			slog.Info("query, 絞り込みの条件, pageを指定した場合は削除された記事を検出しません")
+		} else {
+			for id, local := range locals {
+				if report.seen[id] {
+					continue
+				}
+				plan.addDelete(local.dir)
+				if opts.site != nil {
+					mdPath, assetDir, _ := opts.site.paths(local.article)
+					plan.addDelete(mdPath)
+					plan.addDelete(assetDir)
+				}
+			}
+		}
+	}
+
+	for _, g := range groups {
+		plan.Groups = append(plan.Groups, *g)
+	}
+	slices.SortFunc(plan.Groups, func(a, b planGroup) int { return strings.Compare(a.Name, b.Name) })
+	slices.Sort(plan.Files.Create)
+	slices.Sort(plan.Files.Overwrite)
+	slices.Sort(plan.Files.Delete)
+
+	r := &plan.Requests
+	r.API = r.Listing + r.Comments + r.CommentReactions + r.ArticleReactions
+	if rl, ok := api.RateLimit(); ok {
+		plan.RateLimit = &rl
+	}
+	// 一覧のリクエストの平均の所要時間から、残りのAPIのリクエストとアセットのダウンロードの時間を見積もる
+	latency := elapsed / time.Duration(max(1, r.Listing))
+	plan.EstimatedSeconds = api.EstimateDuration(r.API-r.Listing, r.Assets, latency).Seconds()
+
+	plan.print()
+	opts.stats.plan = plan
+	if opts.planPath != "" {
+		if err := writeJSONFile(opts.planPath, plan); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		slog.Info("計画を保存しました", "path", opts.planPath)
+	}
+	return nil
+}
+
+// 保存する記事のリクエスト数とファイルを計画に加える
+func (p *exportPlan) addArticle(api *repository.QiitaAPI, v *models.Article, groups map[string]*planGroup, opts exportOptions, locals map[string]localArticle) {
+	name := groupDirName(v)
+	g, ok := groups[name]
+	if !ok {
+		g = &planGroup{Name: name}
+		groups[name] = g
+	}
+
+	assetURLs := api.ExtractAssetURLs(v.Body, v.RenderedBody)
+	p.Articles++
+	g.Articles++
+	g.Comments += v.CommentsCount
+	g.Reactions += v.ReactionsCount
+	g.Assets += len(assetURLs)
+
+	// コメント, 絵文字リアクションは1ページ100件, コメントの絵文字リアクションは1ページに収まる想定
+	p.Requests.Comments += max(1, pages(v.CommentsCount))
+	p.Requests.CommentReactions += v.CommentsCount
+	p.Requests.ArticleReactions += max(1, pages(v.ReactionsCount))
+
+	artDir := articleDir(opts.outputDir, v)
+	sanitizedTitle := sanitizeFilename(v.Title)
+	p.addWrite(filepath.Join(artDir, sanitizedTitle+"_metadata.json"))
+	p.addWrite(filepath.Join(artDir, sanitizedTitle+".md"))
+
+	names := repository.AssetFileNames(assetURLs)
+	for _, u := range assetURLs {
+		if opts.assetStore == nil {
+			p.Requests.Assets++
+		} else if _, ok := opts.assetStore.Lookup(u); !ok {
+			p.Requests.Assets++
+		}
+		if opts.assetStore == nil || opts.assetStoreMode == assetStoreModeLink {
+			p.addWrite(filepath.Join(artDir, names[u]))
+		}
+	}
+
+	if opts.site != nil {
+		mdPath, assetDir, _ := opts.site.paths(v)
+		p.addWrite(mdPath)
+		for _, u := range assetURLs {
+			p.addWrite(filepath.Join(assetDir, names[u]))
+		}
+	}
+
+	// タイトルやグループが変わった場合の古いファイル (removeStaleFiles と同じ判定)
+	if local, ok := locals[v.ID]; ok {
+		if local.dir != artDir {
+			p.addDelete(local.dir)
+		} else if oldBase := strings.TrimSuffix(filepath.Base(local.metadataPath), "_metadata.json"); oldBase != sanitizedTitle {
+			p.addDelete(local.metadataPath)
+			p.addDelete(filepath.Join(local.dir, oldBase+".md"))
+		}
+	}
+}
+
+// 100件ごとのページ数
+func pages(n int) int {
+	return (n + 99) / 100
+}
+
+// 既存のファイルは上書き, それ以外は作成として記録する
+func (p *exportPlan) addWrite(path string) {
+	if _, err := os.Stat(path); err == nil {
+		p.Files.Overwrite = append(p.Files.Overwrite, path)
+	} else {
+		p.Files.Create = append(p.Files.Create, path)
+	}
+}
+
+// 存在するファイル, ディレクトリのみ削除として記録する
+func (p *exportPlan) addDelete(path string) {
+	if _, err := os.Stat(path); err == nil {
+		p.Files.Delete = append(p.Files.Delete, path)
+	}
+}
+
+// 計画をログに出力する (標準出力には実行結果のレポートのみ書き込む)
+func (p *exportPlan) print() {
+	slog.Info("エクスポートの計画 (dry-run)", "articles", p.Articles, "listed", p.Listed, "total_count", p.TotalCount, "skipped", p.Skipped)
+	for _, g := range p.Groups {
+		slog.Info("グループ", "group", g.Name, "articles", g.Articles, "comments", g.Comments, "reactions", g.Reactions, "assets", g.Assets)
+	}
+
+	r := p.Requests
+	slog.Info("APIのリクエスト数", "api", r.API, "listing", r.Listing, "comments", r.Comments,
+		"comment_reactions", r.CommentReactions, "article_reactions", r.ArticleReactions, "assets", r.Assets)
+	if p.RateLimit != nil {
+		slog.Info("レート制限", "remaining", p.RateLimit.Remaining, "limit", p.RateLimit.Limit, "reset", p.RateLimit.Reset.Format(time.DateTime))
+	}
+	slog.Info("見積もりの所要時間", "duration", time.Duration(p.EstimatedSeconds*float64(time.Second)).Round(time.Second).String())
+
+	slog.Info("ファイル", "create", len(p.Files.Create), "overwrite", len(p.Files.Overwrite), "delete", len(p.Files.Delete))
+	for _, path := range p.Files.Create {
+		slog.Info("作成", "path", path)
+	}
+	for _, path := range p.Files.Overwrite {
+		slog.Info("上書き", "path", path)
+	}
+	for _, path := range p.Files.Delete {
+		slog.Info("削除", "path", path)
+	}
+}
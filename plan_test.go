@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+// dry-runは一覧のみ取得し、出力先には何も作成せずに計画を保存する
+func TestPlanExport(t *testing.T) {
+	server := qiitafake.NewServer(testFixtures())
+	defer server.Close()
+	dir := t.TempDir()
+
+	opts := testOptions(dir)
+	opts.dryRun = true
+	opts.planPath = filepath.Join(t.TempDir(), "plan.json")
+	opts.assetStoreDir = filepath.Join(dir, "_assets")
+	opts.assetStoreMode = assetStoreModeLink
+	opts.siteFormat, opts.frontMatter, opts.siteDir = siteFormatHugo, frontMatterYAML, filepath.Join(dir, siteDirName)
+	if err := execute(context.Background(), newTestAPI(t, server), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 {
+		t.Errorf("output dir = %v, %v, want empty", entries, err)
+	}
+	for _, r := range server.Requests() {
+		if !strings.HasPrefix(r, "/api/v2/items?") {
+			t.Errorf("request %s is sent in dry-run", r)
+		}
+	}
+
+	b, err := os.ReadFile(opts.planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan exportPlan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.TotalCount != 3 || plan.Listed != 3 || plan.Articles != 3 || plan.Skipped != 0 {
+		t.Errorf("articles = %+v", plan)
+	}
+	wantGroups := []planGroup{
+		{Name: noGroupDirName, Articles: 1},
+		{Name: "dev", Articles: 2, Comments: 1, Assets: 1},
+	}
+	if !slices.Equal(plan.Groups, wantGroups) {
+		t.Errorf("groups = %+v, want %+v", plan.Groups, wantGroups)
+	}
+	// 記事ごとにコメントと絵文字リアクションの一覧を1ページ, コメントごとに絵文字リアクションを1件
+	wantRequests := planRequests{Listing: 2, Comments: 3, CommentReactions: 1, ArticleReactions: 3, API: 9, Assets: 1}
+	if plan.Requests != wantRequests {
+		t.Errorf("requests = %+v, want %+v", plan.Requests, wantRequests)
+	}
+	// 記事ごとのメタデータとMarkdown (6件), 記事のディレクトリのアセットのリンク, サイトの記事 (3件) とアセット
+	if len(plan.Files.Create) != 11 || len(plan.Files.Overwrite) != 0 || len(plan.Files.Delete) != 0 {
+		t.Errorf("files = %+v", plan.Files)
+	}
+}
@@ -0,0 +1,82 @@
+package qiitafake
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qiita_export/models"
+)
+
+// フィクスチャ内で起動したサーバーのURL (例: http://127.0.0.1:12345) に置換される文字列
+// 記事本文にアセットのURLを埋め込む場合に利用する
+const ServerURLPlaceholder = "{{server_url}}"
+
+// Fixtures はフェイクサーバーが返すデータ
+type Fixtures struct {
+	Articles         []models.Article                  `json:"articles"`
+	Comments         map[string][]models.Comment       `json:"comments"`          // 記事ID → コメント
+	ArticleReactions map[string][]models.EmojiReaction `json:"article_reactions"` // 記事ID → 絵文字リアクション
+	CommentReactions map[string][]models.EmojiReaction `json:"comment_reactions"` // コメントID → 絵文字リアクション
+	Assets           map[string]Asset                  `json:"assets"`            // パス (例: /files/xxx.png) → アセット
+
+	// チーム単位のリソース
+	AuthenticatedUser *models.AuthenticatedUser       `json:"authenticated_user"` // nilの場合は404を返す
+	TeamMemberships   []models.TeamMembership         `json:"team_memberships"`
+	TeamInvitations   []models.TeamInvitation         `json:"team_invitations"`
+	Groups            []models.Group                  `json:"groups"`
+	GroupMembers      map[string][]models.GroupMember `json:"group_members"` // グループのurl_name → メンバー
+	Tags              []models.Tag                    `json:"tags"`
+	Templates         []models.Template               `json:"templates"`
+	Projects          []models.Project                `json:"projects"`
+	ProjectComments   map[string][]models.Comment     `json:"project_comments"` // プロジェクトID → コメント
+}
+
+// Asset はアセットURLで配信するファイル
+type Asset struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"` // JSONではbase64で表現する
+}
+
+// JSONファイルからフィクスチャを読み込む
+func LoadFixtures(path string) (Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var f Fixtures
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Fixtures{}, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+
+	return f, nil
+}
+
+// 本文中のプレースホルダーをサーバーのURLに置換したコピーを返す
+func (f Fixtures) resolve(serverURL string) Fixtures {
+	r := strings.NewReplacer(ServerURLPlaceholder, serverURL)
+
+	resolved := f
+	resolved.Articles = make([]models.Article, len(f.Articles))
+	for i, v := range f.Articles {
+		v.Body = r.Replace(v.Body)
+		v.RenderedBody = r.Replace(v.RenderedBody)
+		v.URL = r.Replace(v.URL)
+		resolved.Articles[i] = v
+	}
+
+	resolved.Comments = make(map[string][]models.Comment, len(f.Comments))
+	for id, comments := range f.Comments {
+		cs := make([]models.Comment, len(comments))
+		for i, v := range comments {
+			v.Body = r.Replace(v.Body)
+			v.RenderedBody = r.Replace(v.RenderedBody)
+			cs[i] = v
+		}
+		resolved.Comments[id] = cs
+	}
+
+	return resolved
+}
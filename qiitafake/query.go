@@ -0,0 +1,119 @@
+package qiitafake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qiita_export/models"
+)
+
+// 記事の一覧のqueryの条件
+// Qiitaの検索オプションのうち、以下の条件のみ対応する (全ての条件をANDで評価する)
+//
+//	tag:Go                 タグ (大文字小文字を区別しない)
+//	user:alice             投稿者のID
+//	group:infra            グループのurl_name
+//	title:設計              タイトルに含む
+//	body:設計               本文に含む
+//	created:>=2021-01-01   作成日 (>=, >, <=, <, 省略時は一致)
+//	updated:<2022-01-01    更新日 (createdと同じ)
+//	設計                    タイトルか本文に含む
+//
+// 日付は記事の作成日時, 更新日時のタイムゾーンでの日付で比較する
+type query struct {
+	conds []func(*models.Article) bool
+}
+
+// queryを解析する, 対応していない条件はエラーにする
+func parseQuery(s string) (*query, error) {
+	q := &query{}
+	for _, term := range strings.Fields(s) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok {
+			q.conds = append(q.conds, func(v *models.Article) bool {
+				return strings.Contains(v.Title, term) || strings.Contains(v.Body, term)
+			})
+			continue
+		}
+		if value == "" {
+			return nil, fmt.Errorf("query: %q has no value", term)
+		}
+
+		switch key {
+		case "tag":
+			q.conds = append(q.conds, func(v *models.Article) bool {
+				for _, t := range v.Tags {
+					if strings.EqualFold(t.Name, value) {
+						return true
+					}
+				}
+				return false
+			})
+		case "user":
+			q.conds = append(q.conds, func(v *models.Article) bool {
+				return v.User != nil && v.User.ID == value
+			})
+		case "group":
+			q.conds = append(q.conds, func(v *models.Article) bool {
+				return v.Group != nil && v.Group.URLName == value
+			})
+		case "title":
+			q.conds = append(q.conds, func(v *models.Article) bool { return strings.Contains(v.Title, value) })
+		case "body":
+			q.conds = append(q.conds, func(v *models.Article) bool { return strings.Contains(v.Body, value) })
+		case "created", "updated":
+			cmp, err := parseDateCond(value)
+			if err != nil {
+				return nil, fmt.Errorf("query: %s: %w", term, err)
+			}
+			q.conds = append(q.conds, func(v *models.Article) bool {
+				t := v.CreatedAt
+				if key == "updated" {
+					t = v.UpdatedAt
+				}
+				return cmp(t.Format("2006-01-02"))
+			})
+		default:
+			return nil, fmt.Errorf("query: %q is not supported by the fake server", key)
+		}
+	}
+	return q, nil
+}
+
+// 全ての条件に一致する場合はtrue
+func (q *query) match(v *models.Article) bool {
+	for _, cond := range q.conds {
+		if !cond(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// >=2021-01-01 のような日付の条件を、YYYY-MM-DDの日付を比較する関数にする
+func parseDateCond(value string) (func(date string) bool, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, prefix) {
+			op, value = prefix, value[len(prefix):]
+			break
+		}
+	}
+	if len(value) != len("2006-01-02") || value[4] != '-' || value[7] != '-' {
+		return nil, fmt.Errorf("date %q must be YYYY-MM-DD", value)
+	}
+
+	// YYYY-MM-DDの文字列は辞書順で比較できる
+	switch op {
+	case ">=":
+		return func(date string) bool { return date >= value }, nil
+	case "<=":
+		return func(date string) bool { return date <= value }, nil
+	case ">":
+		return func(date string) bool { return date > value }, nil
+	case "<":
+		return func(date string) bool { return date < value }, nil
+	default:
+		return func(date string) bool { return date == value }, nil
+	}
+}
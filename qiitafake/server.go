@@ -0,0 +1,435 @@
+// Package qiitafake はQiita Team APIのフェイクサーバー
+// テストやオフラインでの動作確認のため、フィクスチャのデータを /api/v2 配下で配信する
+package qiitafake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	maxPage        = 100
+)
+
+// Fault はリクエストに対して強制的に返すエラーレスポンス
+type Fault struct {
+	Path       string        // 対象のパスの前方一致 (例: /api/v2/items), 空の場合は全てのリクエストが対象
+	Status     int           // 返すステータスコード (403, 429, 500 など)
+	Times      int           // 発生させる回数, 0以下の場合は常に発生させる
+	RetryAfter time.Duration // 0より大きい場合はRetry-Afterヘッダーを付与する
+}
+
+// Server はフェイクのQiita Team APIサーバー
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	fixtures  Fixtures
+	token     string
+	faults    []*fault
+	rateLimit *rateLimit
+	requests  []string
+}
+
+type fault struct {
+	Fault
+	hits int
+}
+
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// ServerOption はフェイクサーバーの設定を変更する関数
+type ServerOption func(*Server)
+
+// WithToken は指定したアクセストークン以外のリクエストを401で拒否する
+func WithToken(token string) ServerOption {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// フィクスチャを配信するフェイクサーバーを起動する
+// 利用後はCloseを呼び出すこと
+func NewServer(f Fixtures, opts ...ServerOption) *Server {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(s.Handler())
+	s.fixtures = f.resolve(s.URL)
+
+	return s
+}
+
+// QiitaAPIのベースURL (例: http://127.0.0.1:12345/api/v2)
+func (s *Server) BaseURL() string {
+	return s.URL + "/api/v2"
+}
+
+// 以降のリクエストに対してエラーレスポンスを返すようにする
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{Fault: f})
+}
+
+// Rate-Limit, Rate-Remaining, Rate-Reset ヘッダーを返すようにする
+// APIへのリクエスト毎にremainingが減り、remainingが0の状態でリクエストすると403を返す
+func (s *Server) SetRateLimit(limit, remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = &rateLimit{limit: limit, remaining: remaining, reset: reset}
+}
+
+// これまでに受信したリクエストのパスとクエリ
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// フェイクサーバーのハンドラー
+// httptest.Server以外で配信する場合に利用する
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/items", s.handleItems)
+	mux.HandleFunc("GET /api/v2/items/{id}", s.handleItem)
+	mux.HandleFunc("GET /api/v2/items/{id}/comments", s.handleComments)
+	mux.HandleFunc("GET /api/v2/items/{id}/reactions", s.handleArticleReactions)
+	mux.HandleFunc("GET /api/v2/comments/{id}/reactions", s.handleCommentReactions)
+	mux.HandleFunc("GET /api/v2/authenticated_user", s.handleAuthenticatedUser)
+	mux.HandleFunc("GET /api/v2/team_memberships", listHandler(s, func(f Fixtures) []models.TeamMembership { return f.TeamMemberships }))
+	mux.HandleFunc("GET /api/v2/team_invitations", listHandler(s, func(f Fixtures) []models.TeamInvitation { return f.TeamInvitations }))
+	mux.HandleFunc("GET /api/v2/groups", listHandler(s, func(f Fixtures) []models.Group { return f.Groups }))
+	mux.HandleFunc("GET /api/v2/groups/{url_name}/members", s.handleGroupMembers)
+	mux.HandleFunc("GET /api/v2/tags", listHandler(s, func(f Fixtures) []models.Tag { return f.Tags }))
+	mux.HandleFunc("GET /api/v2/templates", listHandler(s, func(f Fixtures) []models.Template { return f.Templates }))
+	mux.HandleFunc("GET /api/v2/projects", listHandler(s, func(f Fixtures) []models.Project { return f.Projects }))
+	mux.HandleFunc("GET /api/v2/projects/{id}/comments", s.handleProjectComments)
+	mux.HandleFunc("GET /", s.handleAsset)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.intercept(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// 記録, 認証, エラーの注入, レート制限を処理する
+// レスポンスを書き込んだ場合はtrueを返す
+func (s *Server) intercept(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.URL.RequestURI())
+
+	for _, f := range s.faults {
+		if (f.Times > 0 && f.hits >= f.Times) || !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		f.hits++
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+		writeError(w, f.Status, http.StatusText(f.Status), errorType(f.Status))
+		return true
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "unauthorized")
+		return true
+	}
+
+	if rl := s.rateLimit; rl != nil {
+		// リセット時刻を過ぎたら、Qiitaと同様に1時間の枠で上限まで回復させる
+		if now := time.Now(); !now.Before(rl.reset) {
+			rl.remaining = rl.limit
+			rl.reset = now.Add(time.Hour)
+		}
+		// 残りがない場合は減らさずに403を返す (上限の回数までは成功する)
+		exceeded := rl.remaining <= 0
+		if !exceeded {
+			rl.remaining--
+		}
+		w.Header().Set("Rate-Limit", strconv.Itoa(rl.limit))
+		w.Header().Set("Rate-Remaining", strconv.Itoa(rl.remaining))
+		w.Header().Set("Rate-Reset", strconv.FormatInt(rl.reset.Unix(), 10))
+		if exceeded {
+			writeError(w, http.StatusForbidden, "Rate limit exceeded", "rate_limit_exceeded")
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryを指定した場合は条件に一致する記事のみ返す (対応する条件は parseQuery を参照)
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r.URL.Query().Get("query"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "bad_request")
+		return
+	}
+
+	s.mu.Lock()
+	var articles []models.Article
+	for _, v := range s.fixtures.Articles {
+		if q.match(&v) {
+			articles = append(articles, v)
+		}
+	}
+	s.mu.Unlock()
+
+	writePage(w, r, articles)
+}
+
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.fixtures.Articles {
+		if v.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, v)
+			return
+		}
+	}
+	writeNotFound(w)
+}
+
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	exists := s.hasArticle(r.PathValue("id"))
+	comments := s.fixtures.Comments[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !exists {
+		writeNotFound(w)
+		return
+	}
+	writePage(w, r, comments)
+}
+
+func (s *Server) handleArticleReactions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	exists := s.hasArticle(r.PathValue("id"))
+	reactions := s.fixtures.ArticleReactions[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !exists {
+		writeNotFound(w)
+		return
+	}
+	writePage(w, r, reactions)
+}
+
+func (s *Server) handleCommentReactions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	exists := s.hasComment(r.PathValue("id"))
+	reactions := s.fixtures.CommentReactions[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !exists {
+		writeNotFound(w)
+		return
+	}
+	writePage(w, r, reactions)
+}
+
+// フィクスチャの一覧をページングして返すハンドラー
+func listHandler[T any](s *Server, list func(Fixtures) []T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		items := list(s.fixtures)
+		s.mu.Unlock()
+
+		writePage(w, r, items)
+	}
+}
+
+func (s *Server) handleAuthenticatedUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	user := s.fixtures.AuthenticatedUser
+	s.mu.Unlock()
+
+	if user == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) handleGroupMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	exists := false
+	for _, g := range s.fixtures.Groups {
+		exists = exists || g.URLName == r.PathValue("url_name")
+	}
+	members := s.fixtures.GroupMembers[r.PathValue("url_name")]
+	s.mu.Unlock()
+
+	if !exists {
+		writeNotFound(w)
+		return
+	}
+	writePage(w, r, members)
+}
+
+func (s *Server) handleProjectComments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	exists := false
+	for _, p := range s.fixtures.Projects {
+		exists = exists || strconv.Itoa(p.ID) == r.PathValue("id")
+	}
+	comments := s.fixtures.ProjectComments[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !exists {
+		writeNotFound(w)
+		return
+	}
+	writePage(w, r, comments)
+}
+
+func (s *Server) handleAsset(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	asset, ok := s.fixtures.Assets[r.URL.Path]
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(asset.Body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(asset.Body)
+}
+
+func (s *Server) hasArticle(id string) bool {
+	for _, v := range s.fixtures.Articles {
+		if v.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) hasComment(id string) bool {
+	for _, comments := range s.fixtures.Comments {
+		for _, v := range comments {
+			if v.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// page, per_pageに従ってページングし、Total-CountとLinkヘッダーを付与して返す
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, perPage, err := parsePaging(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "bad_request")
+		return
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	lastPage := max(1, (len(items)+perPage-1)/perPage)
+
+	links := []string{
+		pageLink(r, 1, perPage, "first"),
+		pageLink(r, lastPage, perPage, "last"),
+	}
+	if page > 1 {
+		links = append(links, pageLink(r, page-1, perPage, "prev"))
+	}
+	if page < lastPage {
+		links = append(links, pageLink(r, page+1, perPage, "next"))
+	}
+
+	w.Header().Set("Total-Count", strconv.Itoa(len(items)))
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	result := items[start:end]
+	if result == nil {
+		result = []T{}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func parsePaging(q url.Values) (page, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 || page > maxPage {
+			return 0, 0, fmt.Errorf("page must be between 1 and %d", maxPage)
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > maxPerPage {
+			return 0, 0, fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
+
+func pageLink(r *http.Request, page, perPage int, rel string) string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("per_page", strconv.Itoa(perPage))
+
+	u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Qiita APIと同じ {"message": ..., "type": ...} 形式でエラーを返す
+func writeError(w http.ResponseWriter, status int, message, typ string) {
+	writeJSON(w, status, map[string]string{"message": message, "type": typ})
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "Not found", "not_found")
+}
+
+func errorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusTooManyRequests:
+		return "rate_limit_exceeded"
+	default:
+		return "internal_server_error"
+	}
+}
@@ -0,0 +1,185 @@
+package qiitafake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+func get(t *testing.T, rawURL string) *http.Response {
+	t.Helper()
+	res, err := http.Get(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+// 3件の記事のフィクスチャ
+// examples/fake_server/fixtures.json と同じくJSONで記述する
+const testFixturesJSON = `{"articles": [
+	{"id": "a", "title": "Goの設計", "body": "本文", "created_at": "2021-01-01T09:00:00+09:00", "updated_at": "2022-06-01T09:00:00+09:00",
+	 "tags": [{"name": "Go"}], "user": {"id": "alice"}, "group": {"url_name": "dev"}},
+	{"id": "b", "title": "インフラ", "body": "Goを使う", "created_at": "2021-06-15T09:00:00+09:00", "updated_at": "2021-06-15T09:00:00+09:00",
+	 "tags": [{"name": "AWS"}], "user": {"id": "bob"}, "group": {"url_name": "infra"}},
+	{"id": "c", "title": "雑談", "body": "", "created_at": "2022-01-01T00:30:00+09:00", "updated_at": "2022-01-01T00:30:00+09:00",
+	 "tags": [{"name": "go"}], "user": {"id": "alice"}}
+]}`
+
+func testFixtures(t *testing.T) Fixtures {
+	t.Helper()
+	var f Fixtures
+	if err := json.Unmarshal([]byte(testFixturesJSON), &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestItemsQuery(t *testing.T) {
+	server := NewServer(testFixtures(t))
+	defer server.Close()
+
+	tests := []struct {
+		query      string
+		wantIDs    []string
+		wantStatus int
+	}{
+		{"", []string{"a", "b", "c"}, http.StatusOK},
+		{"tag:go", []string{"a", "c"}, http.StatusOK},
+		{"user:alice", []string{"a", "c"}, http.StatusOK},
+		{"group:infra", []string{"b"}, http.StatusOK},
+		{"created:>=2021-06-15", []string{"b", "c"}, http.StatusOK},
+		{"created:>2021-06-15", []string{"c"}, http.StatusOK},
+		{"created:<2021-06-15", []string{"a"}, http.StatusOK},
+		// 作成日時のタイムゾーンの日付で比較する
+		{"created:2022-01-01", []string{"c"}, http.StatusOK},
+		{"updated:>=2022-01-01 user:alice", []string{"a", "c"}, http.StatusOK},
+		{"Go", []string{"a", "b"}, http.StatusOK},
+		{"title:Go tag:Go", []string{"a"}, http.StatusOK},
+		{"stocks:>10", nil, http.StatusBadRequest},
+		{"created:2021", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res := get(t, server.BaseURL()+"/items?query="+url.QueryEscape(tt.query))
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var articles []models.Article
+			if err := json.NewDecoder(res.Body).Decode(&articles); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, v := range articles {
+				ids = append(ids, v.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if got := res.Header.Get("Total-Count"); got != strconv.Itoa(len(tt.wantIDs)) {
+				t.Errorf("Total-Count = %s, want %d", got, len(tt.wantIDs))
+			}
+		})
+	}
+}
+
+func TestPaging(t *testing.T) {
+	server := NewServer(testFixtures(t))
+	defer server.Close()
+
+	tests := []struct {
+		query    string
+		wantLen  int
+		wantNext bool
+		wantCode int
+	}{
+		{"page=1&per_page=2", 2, true, http.StatusOK},
+		{"page=2&per_page=2", 1, false, http.StatusOK},
+		{"page=3&per_page=2", 0, false, http.StatusOK},
+		{"page=0", 0, false, http.StatusBadRequest},
+		{"per_page=101", 0, false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res := get(t, server.BaseURL()+"/items?"+tt.query)
+			if res.StatusCode != tt.wantCode {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var articles []models.Article
+			if err := json.NewDecoder(res.Body).Decode(&articles); err != nil {
+				t.Fatal(err)
+			}
+			if len(articles) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(articles), tt.wantLen)
+			}
+			if got := res.Header.Get("Total-Count"); got != "3" {
+				t.Errorf("Total-Count = %s, want 3", got)
+			}
+			link := res.Header.Get("Link")
+			if hasNext := strings.Contains(link, `rel="next"`); hasNext != tt.wantNext {
+				t.Errorf("Link = %s, want next=%v", link, tt.wantNext)
+			}
+		})
+	}
+}
+
+// 残りの回数まで成功し、その後は403を返す
+func TestRateLimit(t *testing.T) {
+	server := NewServer(testFixtures(t))
+	defer server.Close()
+	server.SetRateLimit(10, 3, time.Now().Add(time.Hour))
+
+	var statuses []int
+	var remaining []string
+	for range 4 {
+		res := get(t, server.BaseURL()+"/items")
+		statuses = append(statuses, res.StatusCode)
+		remaining = append(remaining, res.Header.Get("Rate-Remaining"))
+	}
+	if want := []int{200, 200, 200, 403}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if want := []string{"2", "1", "0", "0"}; !slices.Equal(remaining, want) {
+		t.Errorf("Rate-Remaining = %v, want %v", remaining, want)
+	}
+
+	// アセットはレート制限の対象外
+	if res := get(t, server.URL+"/files/none.png"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("asset status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestFault(t *testing.T) {
+	server := NewServer(testFixtures(t))
+	defer server.Close()
+	server.InjectFault(Fault{Path: "/api/v2/items", Status: http.StatusTooManyRequests, Times: 2, RetryAfter: 3 * time.Second})
+
+	var statuses []int
+	for range 3 {
+		res := get(t, server.BaseURL()+"/items")
+		statuses = append(statuses, res.StatusCode)
+		if res.StatusCode == http.StatusTooManyRequests && res.Header.Get("Retry-After") != "3" {
+			t.Errorf("Retry-After = %q, want 3", res.Header.Get("Retry-After"))
+		}
+	}
+	if want := []int{429, 429, 200}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}
@@ -2,11 +2,12 @@ package main
 
 import (
 	"encoding/csv"
-	"flag"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/qiita_export/repository"
 )
 
 // Replacement 定義
@@ -16,34 +17,35 @@ type Replacement struct {
 	Count  int
 }
 
-func main() {
-	// コマンドライン引数を定義
-	rootDir := flag.String("dir", "", "Directory to scan for markdown files")
-	csvFile := flag.String("csv", "", "CSV file with replacement mappings")
-	flag.Parse()
+// replace-linksコマンド
+// CSV (旧URL,新URL) の対応表に従って、出力ディレクトリ内のMarkdownのURLを置換する
+// Qiitaに昔存在した旧projects機能のURLを、リダイレクト先に置き換えるために使う
+func runReplaceLinks(args []string) error {
+	fs := newFlagSet("replace-links")
+	rootDir := addDirFlag(fs)
+	csvFile := fs.String("csv", "", "置換の対応表のCSVファイル (旧URL,新URL)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
 
-	if *rootDir == "" || *csvFile == "" {
-		flag.Usage()
-		os.Exit(1)
+	if *csvFile == "" {
+		fs.Usage()
+		return errors.New("-csv is required")
 	}
 
 	// CSVファイルを読み込む
 	replacements, err := loadReplacements(*csvFile)
 	if err != nil {
-		fmt.Printf("Error loading replacements: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error loading replacements: %w", err)
 	}
 
-	err = filepath.WalkDir(*rootDir, func(path string, d os.DirEntry, err error) error {
-		return processFile(path, d, err, replacements)
-	})
-	if err != nil {
-		fmt.Printf("Error walking directory: %v\n", err)
-		os.Exit(1)
+	if err := replaceLinksInDir(*rootDir, replacements); err != nil {
+		return err
 	}
 
 	// 結果の表示
 	printResults(replacements)
+	return nil
 }
 
 func loadReplacements(csvFile string) ([]Replacement, error) {
@@ -74,18 +76,27 @@ func loadReplacements(csvFile string) ([]Replacement, error) {
 	return replacements, nil
 }
 
-func processFile(path string, d os.DirEntry, err error, replacements []Replacement) error {
+// エクスポートした記事のMarkdownのURLを置換する
+// 記事のMarkdownのみ対象にする (静的サイトの出力などは置換しない)
+func replaceLinksInDir(dir string, replacements []Replacement) error {
+	exported, err := repository.LoadExportedArticles(dir)
 	if err != nil {
-		return err
+		return fmt.Errorf("error loading exported articles: %w", err)
 	}
-
-	// ディレクトリまたは.mdファイル以外はスキップ
-	if d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".md") {
-		return nil
+	for _, e := range exported {
+		if err := processFile(e.MarkdownPath(), replacements); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// ファイルの内容を読み込み
+func processFile(path string, replacements []Replacement) error {
+	// ファイルの内容を読み込み (Markdownのない記事はスキップ)
 	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("error reading file %s: %v", path, err)
 	}
@@ -106,8 +117,11 @@ func processFile(path string, d os.DirEntry, err error, replacements []Replaceme
 
 	// 置換が行われた場合のみファイルを更新
 	if fileChanged {
-		err = os.WriteFile(path, []byte(newContent), d.Type())
+		info, err := os.Stat(path)
 		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, []byte(newContent), info.Mode().Perm()); err != nil {
 			return fmt.Errorf("error writing file %s: %v", path, err)
 		}
 	}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/qiita_export/repository"
+)
+
+// Qiitaの記事のURL (チームのドメイン <team>.qiita.com と qiita.com)
+var itemsRegexp = regexp.MustCompile(`https://(?:[0-9a-z-]+\.)?qiita\.com/[^/]+/items/([0-9a-z]+)(#[^)\s]*)?`)
+
+// replace-refsコマンド
+// Markdown内のQiitaの記事へのリンクを、エクスポートしたMarkdownの出力ディレクトリからのパスに置換する
+func runReplaceRefs(args []string) error {
+	fs := newFlagSet("replace-refs")
+	root := addDirFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	replCount, err := replaceRefsInDir(*root)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("置換が完了しました", "files", replCount)
+	return nil
+}
+
+// エクスポートした記事のMarkdownのリンクを置換し、変更したファイルの数を返す
+// 記事のMarkdownのみ対象にする (静的サイトの出力などは置換しない)
+func replaceRefsInDir(root string) (int, error) {
+	exported, err := repository.LoadExportedArticles(root)
+	if err != nil {
+		return 0, err
+	}
+
+	// IDと相対パスのマップを用意
+	pathMap, err := createPathMap(root, exported)
+	if err != nil {
+		return 0, err
+	}
+
+	replCount := 0
+	for _, e := range exported {
+		path := e.MarkdownPath()
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return replCount, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+
+		// 置換を実行
+		newContent := replaceRefs(string(content), pathMap)
+
+		// 変更があった場合のみ書き込み
+		if newContent != string(content) {
+			replCount++
+			info, err := os.Stat(path)
+			if err != nil {
+				return replCount, err
+			}
+			if err := writeFileAtomic(path, []byte(newContent), info.Mode().Perm()); err != nil {
+				return replCount, fmt.Errorf("failed to write file %s: %w", path, err)
+			}
+			slog.Info("リンクを置換しました", "path", path)
+		}
+	}
+
+	return replCount, nil
+}
+
+// 記事IDと、出力ディレクトリからのMarkdownのパス (/<group>/<id>/<title>.md) のマップを作成する
+func createPathMap(dir string, exported []repository.ExportedArticle) (map[string]string, error) {
+	pathMap := make(map[string]string, len(exported))
+	for _, e := range exported {
+		rel, err := filepath.Rel(dir, e.MarkdownPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		// マークダウンで使用可能な相対パスを生成
+		markdownRelativePath := "/" + filepath.ToSlash(rel)
+
+		// 空白が含まれている場合、<>で囲まなければ認識されない
+		if strings.Contains(markdownRelativePath, " ") {
+			markdownRelativePath = fmt.Sprintf("<%s>", markdownRelativePath)
+		}
+
+		pathMap[e.Article.ID] = markdownRelativePath
+	}
+
+	return pathMap, nil
+}
+
+// 文章を受け取り、正規表現でqiitaドメインのitemsを置換した値を取得する
+func replaceRefs(body string, pathMap map[string]string) string {
+	return itemsRegexp.ReplaceAllStringFunc(body, func(s string) string {
+		matches := itemsRegexp.FindStringSubmatch(s)
+		if len(matches) < 2 {
+			return s
+		}
+
+		id := matches[1]
+		fragment := ""
+		if len(matches) > 2 && matches[2] != "" {
+			fragment = matches[2] // #が含まれた状態で取得される
+		}
+
+		if path, exists := pathMap[id]; exists {
+			return fmt.Sprintf("%s%s", path, fragment)
+		}
+
+		return s
+	})
+}
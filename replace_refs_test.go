@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+)
+
+// エクスポート済みの記事と、記事以外のMarkdown (静的サイトの出力) を用意する
+func writeReplaceFixtures(t *testing.T, body string) (dir string, article, other string) {
+	t.Helper()
+	dir = t.TempDir()
+	updated := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	articles := []models.Article{
+		testArticle(testID1, "リンク元", "dev", updated),
+		testArticle(testID2, "リンク 先", "dev", updated),
+	}
+	for _, v := range articles {
+		artDir := articleDir(dir, &v)
+		if err := os.MkdirAll(artDir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := downloadArticleToLocal(&v, artDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	article = filepath.Join(articleDir(dir, &articles[0]), sanitizeFilename(articles[0].Title)+".md")
+	other = filepath.Join(dir, siteDirName, "content", "posts", testID1, "index.md")
+	for _, p := range []string{article, other} {
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, article, other
+}
+
+func TestReplaceRefsInDir(t *testing.T) {
+	body := "[先](https://example.qiita.com/bob/items/" + testID2 + "#見出し) [不明](https://example.qiita.com/bob/items/ffffffffffffffffffff)"
+	dir, article, other := writeReplaceFixtures(t, body)
+
+	n, err := replaceRefsInDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("replaced files = %d, want 1", n)
+	}
+	want := "[先](</dev/" + testID2 + "/リンク 先.md>#見出し) [不明](https://example.qiita.com/bob/items/ffffffffffffffffffff)"
+	if b, _ := os.ReadFile(article); string(b) != want {
+		t.Errorf("article = %q, want %q", b, want)
+	}
+	if b, _ := os.ReadFile(other); string(b) != body {
+		t.Errorf("markdown outside the articles is rewritten: %q", b)
+	}
+}
+
+func TestReplaceLinksInDir(t *testing.T) {
+	body := "https://example.qiita.com/projects/1 https://example.qiita.com/projects/1"
+	dir, article, other := writeReplaceFixtures(t, body)
+
+	replacements := []Replacement{
+		{OldURL: "https://example.qiita.com/projects/1", NewURL: "https://example.qiita.com/dev/items/" + testID2},
+		{OldURL: "https://example.qiita.com/projects/2", NewURL: "https://example.com/"},
+	}
+	if err := replaceLinksInDir(dir, replacements); err != nil {
+		t.Fatal(err)
+	}
+	if replacements[0].Count != 2 || replacements[1].Count != 0 {
+		t.Errorf("counts = %+v", replacements)
+	}
+	if b, _ := os.ReadFile(article); strings.Contains(string(b), "projects") {
+		t.Errorf("article = %q", b)
+	}
+	if b, _ := os.ReadFile(other); string(b) != body {
+		t.Errorf("markdown outside the articles is rewritten: %q", b)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/qiita_export/repository"
+)
+
+// 実行結果のステータス
+const (
+	reportStatusOK          = "ok"
+	reportStatusFailed      = "failed"
+	reportStatusInterrupted = "interrupted" // Ctrl-C, -timeout で中断した
+)
+
+// export, syncの実行結果のレポート
+// 実行の最後に標準出力にJSONで出力し、-report を指定した場合はファイルにも保存する (CIでの集計など)
+// エラーで終了した場合も、その時点までの件数を出力する
+type runReport struct {
+	Command         string                `json:"command"`
+	Status          string                `json:"status"`
+	Error           string                `json:"error,omitempty"`
+	DryRun          bool                  `json:"dry_run"`
+	StartedAt       time.Time             `json:"started_at"`
+	DurationSeconds float64               `json:"duration_seconds"`
+	Articles        reportArticles        `json:"articles"`
+	Assets          reportAssets          `json:"assets"`
+	Requests        int64                 `json:"requests"` // APIとアセットのリクエスト数 (リトライを含む)
+	RateLimit       *repository.RateLimit `json:"rate_limit,omitempty"`
+	Sync            *reportSync           `json:"sync,omitempty"` // syncの場合のみ
+	Plan            *exportPlan           `json:"plan,omitempty"` // dry-runの場合のみ
+}
+
+type reportArticles struct {
+	OK      int64 `json:"ok"`
+	Failed  int64 `json:"failed"`  // アセットのダウンロードに失敗した記事を含む
+	Skipped int64 `json:"skipped"` // 再開時の完了済み, 同期で変更なし, 一覧の取得後に削除された記事
+}
+
+type reportAssets struct {
+	OK        int64 `json:"ok"`
+	Forbidden int64 `json:"forbidden"` // 403 (レート制限を除く)
+	NotFound  int64 `json:"not_found"` // 404
+	Failed    int64 `json:"failed"`    // その他のエラー
+	Skipped   int64 `json:"skipped"`   // 再開時のダウンロード済み
+}
+
+type reportSync struct {
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// エクスポート中の件数の集計
+// 記事とアセットを並行して処理するため、atomicに更新する
+type exportStats struct {
+	articlesOK, articlesFailed, articlesSkipped                            atomic.Int64
+	assetsOK, assetsForbidden, assetsNotFound, assetsFailed, assetsSkipped atomic.Int64
+	// syncの場合のみ, executeでセットする
+	sync *syncReport
+	// dry-runの場合のみ, planExportでセットする
+	plan *exportPlan
+}
+
+// アセット1件の結果を記録する
+func (s *exportStats) addAsset(err error) {
+	switch {
+	case err == nil:
+		s.assetsOK.Add(1)
+	case repository.IsForbidden(err):
+		s.assetsForbidden.Add(1)
+	case repository.IsNotFound(err):
+		s.assetsNotFound.Add(1)
+	default:
+		s.assetsFailed.Add(1)
+	}
+}
+
+// 集計した件数と実行結果からレポートを作成する
+func (s *exportStats) report(ctx context.Context, command string, dryRun bool, start time.Time, api *repository.QiitaAPI, runErr error) *runReport {
+	r := &runReport{
+		Command:         command,
+		Status:          reportStatusOK,
+		DryRun:          dryRun,
+		StartedAt:       start,
+		DurationSeconds: time.Since(start).Seconds(),
+		Articles: reportArticles{
+			OK:      s.articlesOK.Load(),
+			Failed:  s.articlesFailed.Load(),
+			Skipped: s.articlesSkipped.Load(),
+		},
+		Assets: reportAssets{
+			OK:        s.assetsOK.Load(),
+			Forbidden: s.assetsForbidden.Load(),
+			NotFound:  s.assetsNotFound.Load(),
+			Failed:    s.assetsFailed.Load(),
+			Skipped:   s.assetsSkipped.Load(),
+		},
+		Requests: repository.RequestCount.Load(),
+	}
+	if runErr != nil {
+		r.Status = reportStatusFailed
+		if ctx.Err() != nil {
+			r.Status = reportStatusInterrupted
+		}
+		r.Error = runErr.Error()
+	}
+	if rl, ok := api.RateLimit(); ok {
+		r.RateLimit = &rl
+	}
+	if s.sync != nil {
+		r.Sync = s.sync.counts()
+	}
+	r.Plan = s.plan
+	return r
+}
+
+// レポートを標準出力に出力し、pathを指定した場合はファイルにも保存する
+func (r *runReport) write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	if path != "" {
+		if err := writeJSONFile(path, r); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return nil
+}
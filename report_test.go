@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/qiitafake"
+)
+
+// コマンドを実行し、標準出力と標準エラー出力を返す
+func captureOutput(t *testing.T, run func() error) (stdout, stderr string, err error) {
+	t.Helper()
+	dir := t.TempDir()
+	outFile, ferr := os.Create(filepath.Join(dir, "stdout"))
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	defer outFile.Close()
+	errFile, ferr := os.Create(filepath.Join(dir, "stderr"))
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	defer errFile.Close()
+
+	// parseFlagsでデフォルトのロガーが置き換わるため、実行後に戻す
+	origStdout, origStderr, origLogger := os.Stdout, os.Stderr, slog.Default()
+	os.Stdout, os.Stderr = outFile, errFile
+	err = run()
+	os.Stdout, os.Stderr = origStdout, origStderr
+	slog.SetDefault(origLogger)
+
+	o, ferr := os.ReadFile(outFile.Name())
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	e, ferr := os.ReadFile(errFile.Name())
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	return string(o), string(e), err
+}
+
+// -log_format text でも標準出力にはレポートのJSONのみを書き込み、ログは標準エラー出力に書き込む
+func TestRunExportReport(t *testing.T) {
+	server := qiitafake.NewServer(testFixtures())
+	defer server.Close()
+	t.Setenv(models.EnvConfigFileKey, "")
+	t.Setenv(models.EnvProfileKey, "")
+	t.Setenv("ACCESS_TOKEN", "token")
+	t.Setenv("DOMAIN", strings.TrimPrefix(server.URL, "http://"))
+
+	dir := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	tests := []struct {
+		name         string
+		syncDefault  bool
+		wantCommand  string
+		wantArticles reportArticles
+		wantSync     bool
+	}{
+		{"export", false, "export", reportArticles{OK: 3}, false},
+		// 2回目は変更がないため全ての記事をスキップする
+		{"sync", true, "sync", reportArticles{Skipped: 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-dir", dir, "-base_url", server.BaseURL(), "-min_interval", "0", "-log_format", "text", "-report", reportPath}
+			stdout, stderr, err := captureOutput(t, func() error { return runExport(args, tt.syncDefault) })
+			if err != nil {
+				t.Fatalf("runExport() = %v\nstderr:\n%s", err, stderr)
+			}
+			if !strings.Contains(stderr, "level=INFO") {
+				t.Errorf("stderr does not contain text logs: %q", stderr)
+			}
+
+			// 標準出力全体が1つのJSONとして読める (ログの行を含まない)
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(stdout), &fields); err != nil {
+				t.Fatalf("stdout is not a JSON report: %v\n%s", err, stdout)
+			}
+			for _, key := range []string{"command", "status", "dry_run", "started_at", "duration_seconds", "articles", "assets", "requests"} {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("report does not have %s: %s", key, stdout)
+				}
+			}
+			if _, ok := fields["sync"]; ok != tt.wantSync {
+				t.Errorf("sync in report = %v, want %v", ok, tt.wantSync)
+			}
+			if _, ok := fields["error"]; ok {
+				t.Errorf("report of a successful run has error: %s", stdout)
+			}
+
+			var r runReport
+			if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Command != tt.wantCommand || r.Status != reportStatusOK || r.Articles != tt.wantArticles || r.Requests == 0 {
+				t.Errorf("report = %+v", r)
+			}
+
+			// -report のファイルは標準出力と同じ内容
+			b, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, []byte(strings.TrimSuffix(stdout, "\n"))) {
+				t.Errorf("report file = %s, stdout = %s", b, stdout)
+			}
+		})
+	}
+}
@@ -1,257 +1,278 @@
 package repository
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"path/filepath"
-	"regexp"
-	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/qiita_export/internal/workerpool"
 	"github.com/qiita_export/models"
 )
 
-const (
-	sleepTime = 100 * time.Millisecond
-)
-
 // リクエスト回数, QiitaAPIでは1時間あたり1000回がリミットのため、一応記録する
-var RequestCount = 0
+// 並行してリクエストするため、atomicに更新する
+var RequestCount atomic.Int64
 
 type QiitaAPI struct {
 	requestBaseApiUrl string
 	authHeaderToken   string
+	scheme            string
+	userAgent         string
+	client            *http.Client
+	middlewares       []Middleware
+	limiter           *rateLimiter
+	requestTimeout    time.Duration
+	retryPolicy       RetryPolicy
+	concurrency       int
+	semaphore         chan struct{}
+	assetAllowHosts   []string
+	assetDenyHosts    []string
+	assetExtractor    assetExtractor
+	logger            *slog.Logger
+	authHosts         []string // アクセストークンを送信するホスト (APIとチームのドメイン)
 }
 
-func NewQiitaAPI(domain, token string) *QiitaAPI {
-	return &QiitaAPI{
-		requestBaseApiUrl: fmt.Sprintf("https://%s/api/v2", domain),
-		authHeaderToken:   fmt.Sprintf("Bearer %s", token),
+// QiitaAPIを生成する
+// オプション未指定の場合は https://<domain>/api/v2 に http.DefaultClient でリクエストする
+func NewQiitaAPI(domain, token string, opts ...Option) *QiitaAPI {
+	a := &QiitaAPI{
+		authHeaderToken: fmt.Sprintf("Bearer %s", token),
+		scheme:          "https",
+		client:          http.DefaultClient,
+		limiter:         newRateLimiter(),
+		concurrency:     1,
+		retryPolicy:     DefaultRetryPolicy,
+		logger:          slog.Default(),
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.limiter.logger = a.logger
+	a.semaphore = make(chan struct{}, a.concurrency)
+	a.buildBaseURL(domain)
+	a.buildAuthHosts(domain)
+	a.buildClient()
+	a.buildAssetExtractor(domain)
+
+	return a
+}
+
+// APIのベースURLを取得する
+func (a QiitaAPI) BaseURL() string {
+	return a.requestBaseApiUrl
 }
 
-func (a QiitaAPI) newGetRequest(url string) (*http.Request, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// APIへのリクエストを作成する, アクセストークンを付与する
+func (a QiitaAPI) newGetRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, a.wrapError(err)
 	}
 	req.Header.Set("Authorization", a.authHeaderToken)
-	RequestCount++
+	if a.userAgent != "" {
+		req.Header.Set("User-Agent", a.userAgent)
+	}
+	RequestCount.Add(1)
 	return req, nil
 }
 
-func (a QiitaAPI) wrapError(err error) error {
-	return fmt.Errorf("合計リクエスト数: %d, エラー: %w", RequestCount, err)
+// アクセストークンを送信するホストを決める
+// APIのベースURLのホストとチームのドメインのみとし、S3などアセットの他のホストには送らない
+func (a *QiitaAPI) buildAuthHosts(domain string) {
+	a.authHosts = []string{domain}
+	if u, err := url.Parse(a.requestBaseApiUrl); err == nil && u.Host != domain {
+		a.authHosts = append(a.authHosts, u.Host)
+	}
 }
 
-// QiitaAPIを利用して、記事をAPI経由で取得する
-// GET /api/v2/items にリクエストを送信し、格納する
-// https://qiita.com/api/v2/docs#get-apiv2items
-func (a QiitaAPI) RequestArticles(queryParams string) ([]models.Article, int, error) {
-	// url.Valuesを使うと日本語がエンコーディングされてしまうため、そのままクエリパラメータを設定する
-	requestUrl := fmt.Sprintf("%s/items?%s", a.requestBaseApiUrl, queryParams)
-
-	req, err := a.newGetRequest(requestUrl)
-	if err != nil {
-		return nil, -1, a.wrapError(err)
+// アクセストークンを送信してよいホストかどうか
+func (a QiitaAPI) isAuthHost(u *url.URL) bool {
+	for _, h := range a.authHosts {
+		if strings.EqualFold(u.Host, h) {
+			return true
+		}
 	}
+	return false
+}
+
+// APIへのリクエストかどうか
+// アセットのダウンロードなど、APIのベースURL以外へのリクエストはレート制限の対象外
+func (a QiitaAPI) isAPIRequest(u *url.URL) bool {
+	return strings.HasPrefix(u.String(), a.requestBaseApiUrl+"/")
+}
 
-	res, err := http.DefaultClient.Do(req)
+// リトライしながらGETリクエストを送信し、ステータスコードが200以外の場合は*APIErrorを返す
+func (a QiitaAPI) get(ctx context.Context, requestUrl string) (*http.Response, error) {
+	res, err := a.doWithRetry(ctx, requestUrl, a.newGetRequest)
 	if err != nil {
-		return nil, -1, a.wrapError(err)
+		return nil, err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, -1, a.wrapError(fmt.Errorf("failed to get articles: %s, url: %s", res.Status, requestUrl))
+		defer res.Body.Close()
+		return nil, newAPIError(res)
 	}
 
-	total, err := strconv.Atoi(res.Header.Get("Total-Count"))
-	if err != nil {
-		return nil, -1, a.wrapError(err)
+	return res, nil
+}
+
+// レート制限に従って待機してからリクエストを送信し、レスポンスヘッダーからレート制限の状態を更新する
+// 同時に送信するリクエストの数はconcurrencyまでに制限する
+// requestTimeoutが指定されている場合、レスポンスボディを閉じるまでをタイムアウトの対象とする
+func (a QiitaAPI) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	select {
+	case a.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+	defer func() { <-a.semaphore }()
 
-	articles := make([]models.Article, 0, total)
-	if err := json.NewDecoder(res.Body).Decode(&articles); err != nil {
-		return nil, total, err
+	if err := a.limiter.wait(ctx, a.isAPIRequest(req.URL)); err != nil {
+		return nil, err
 	}
 
-	return articles, total, nil
-}
+	cancel := context.CancelFunc(func() {})
+	if a.requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, a.requestTimeout)
+		req = req.WithContext(ctx)
+	}
 
-// ArticleモデルのIDを利用して、絵文字リアクションをAPI経由で取得する
-// GET /api/v2/items/:item_id/reactions にリクエストを送信し、格納する
-// https://qiita.com/api/v2/docs#get-apiv2itemsitem_idreactions
-func (a QiitaAPI) RequestArticleReactions(articleID string) ([]models.EmojiReaction, error) {
-	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, "items", articleID, "reactions")
+	res, err := a.client.Do(req)
 	if err != nil {
-		return nil, a.wrapError(err)
+		cancel()
+		return nil, err
 	}
+	a.limiter.update(res.Header)
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+// レスポンスボディを閉じた時にタイムアウト用のcontextを解放する
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+func (a QiitaAPI) wrapError(err error) error {
+	return fmt.Errorf("合計リクエスト数: %d, エラー: %w", RequestCount.Load(), err)
+}
+
+// QiitaAPIを利用して、記事をAPI経由で取得する
+// GET /api/v2/items にリクエストを送信し、格納する
+// https://qiita.com/api/v2/docs#get-apiv2items
+func (a QiitaAPI) RequestArticles(queryParams string) ([]models.Article, int, error) {
+	return a.RequestArticlesContext(context.Background(), queryParams)
+}
+
+// RequestArticlesのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestArticlesContext(ctx context.Context, queryParams string) ([]models.Article, int, error) {
+	// queryParamsはURLエンコード済みのクエリ文字列 (例: page=1&per_page=100&query=tag%3AGo)
+	requestUrl := fmt.Sprintf("%s/items?%s", a.requestBaseApiUrl, queryParams)
 
-	req, err := a.newGetRequest(requestUrl)
+	page, err := requestPage[models.Article](ctx, a, requestUrl)
 	if err != nil {
-		return nil, a.wrapError(err)
+		return nil, -1, err
+	}
+	if page.Total < 0 {
+		return nil, -1, a.wrapError(fmt.Errorf("Total-Count header is missing, url: %s", requestUrl))
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	return page.Items, page.Total, nil
+}
+
+// 記事の一覧をpageから1ページずつ取得するPagerを返す
+// queryは検索クエリ (URLエンコード前), 空の場合は全ての記事を取得する
+func (a QiitaAPI) ArticlePages(page, perPage int, query string) (*Pager[models.Article], error) {
+	requestUrl := a.requestBaseApiUrl + "/items"
+	if query != "" {
+		requestUrl += "?" + url.Values{"query": {query}}.Encode()
+	}
+	pager, err := newPager[models.Article](a, requestUrl, page, perPage)
 	if err != nil {
-		return nil, a.wrapError(err)
+		return nil, err
 	}
-	defer res.Body.Close()
+	pager.requireTotal = true
+	return pager, nil
+}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, a.wrapError(fmt.Errorf("failed to get emoji reactions: %s", res.Status))
-	}
+// ArticleモデルのIDを利用して、絵文字リアクションをAPI経由で取得する
+// GET /api/v2/items/:item_id/reactions にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2itemsitem_idreactions
+func (a QiitaAPI) RequestArticleReactions(articleID string) ([]models.EmojiReaction, error) {
+	return a.RequestArticleReactionsContext(context.Background(), articleID)
+}
 
-	// 絵文字リアクション情報をEmojiReactionsに格納する
-	reactions := make([]models.EmojiReaction, 0)
-	body, err := io.ReadAll(res.Body)
+// RequestArticleReactionsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestArticleReactionsContext(ctx context.Context, articleID string) ([]models.EmojiReaction, error) {
+	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, "items", articleID, "reactions")
 	if err != nil {
 		return nil, a.wrapError(err)
 	}
-	if err := json.Unmarshal(body, &reactions); err != nil {
-		return nil, a.wrapError(err)
+
+	reactions, err := requestAll[models.EmojiReaction](ctx, a, requestUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji reactions: %w", err)
 	}
 
 	return reactions, nil
 }
 
 // ArticleモデルのIDを利用して、コメントをAPI経由で取得する
-// GET /api/v2/items/:item_id/comments にリクエストを送信し、格納する
+// GET /api/v2/items/:item_id/comments にリクエストを送信し、全ページ分を格納する
 // https://qiita.com/api/v2/docs#get-apiv2itemsitem_idcomments
 func (a QiitaAPI) RequestComments(itemID string) ([]models.Comment, error) {
-	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, "items", itemID, "comments")
-	if err != nil {
-		return nil, a.wrapError(err)
-	}
+	return a.RequestCommentsContext(context.Background(), itemID)
+}
 
-	req, err := a.newGetRequest(requestUrl)
+// RequestCommentsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestCommentsContext(ctx context.Context, itemID string) ([]models.Comment, error) {
+	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, "items", itemID, "comments")
 	if err != nil {
 		return nil, a.wrapError(err)
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	comments, err := requestAll[models.Comment](ctx, a, requestUrl)
 	if err != nil {
-		return nil, a.wrapError(err)
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return nil, a.wrapError(fmt.Errorf("failed to get comments: %s", res.Status))
+		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
 
-	// コメント情報をCommentsに格納する
-	comments := make([]models.Comment, 0)
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, a.wrapError(err)
-	}
-	if err := json.Unmarshal(body, &comments); err != nil {
-		return nil, a.wrapError(err)
-	}
-
-	// コメントの絵文字リアクション情報を取得する
-	for i, v := range comments {
-		reactions, err := a.requestCommentReactions(v.ID)
+	// コメントの絵文字リアクション情報を並行して取得する
+	err = workerpool.Run(len(comments), a.concurrency, func(i int) error {
+		reactions, err := a.requestCommentReactions(ctx, comments[i].ID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get emoji reactions: %w", err)
+			return fmt.Errorf("failed to get emoji reactions: %w", err)
 		}
 		comments[i].EmojiReactions = reactions
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return comments, nil
 }
 
 // コメントモデルのIDを利用して、絵文字リアクションをAPI経由で取得する
-// GET /api/v2/comments/:comment_id/reactions にリクエストを送信し、格納する
+// GET /api/v2/comments/:comment_id/reactions にリクエストを送信し、全ページ分を格納する
 // https://qiita.com/api/v2/docs#get-apiv2commentscomment_idreactions
-func (a QiitaAPI) requestCommentReactions(commentID string) ([]models.EmojiReaction, error) {
+func (a QiitaAPI) requestCommentReactions(ctx context.Context, commentID string) ([]models.EmojiReaction, error) {
 	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, "comments", commentID, "reactions")
 	if err != nil {
 		return nil, a.wrapError(err)
 	}
 
-	req, err := a.newGetRequest(requestUrl)
-	if err != nil {
-		return nil, a.wrapError(err)
-	}
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, a.wrapError(err)
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return nil, a.wrapError(fmt.Errorf("failed to get emoji reactions: %s", res.Status))
-	}
-
-	// 絵文字リアクション情報を格納する
-	emojiReactions := make([]models.EmojiReaction, 0)
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, a.wrapError(err)
-	}
-	if err := json.Unmarshal(body, &emojiReactions); err != nil {
-		return nil, a.wrapError(err)
-	}
-
-	return emojiReactions, nil
-}
-
-// 添付ファイルのダウンロード
-func (a QiitaAPI) DownloadArticleAssets(body, artDir string) (retErr error) {
-	assetRegexp := regexp.MustCompile(os.Getenv("ASSET_REGEXP"))
-
-	count := 0
-	_ = assetRegexp.ReplaceAllStringFunc(body, func(s string) string {
-		count++
-		if retErr != nil {
-			return s
-		}
-
-		f, err := os.Create(filepath.Join(artDir, path.Base(s)))
-		if err != nil {
-			retErr = err
-			return s
-		}
-
-		req, err := a.newGetRequest(s)
-		if err != nil {
-			return s
-		}
-
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			retErr = err
-			return s
-		}
-		defer res.Body.Close()
-
-		if res.StatusCode == 403 {
-			fmt.Println("403:", artDir, s)
-		}
-
-		if _, err := io.Copy(f, res.Body); err != nil {
-			retErr = err
-			return s
-		}
-
-		if err := f.Close(); err != nil {
-			retErr = err
-			return s
-		}
-
-		// レート制限で403になってしまうため待機時間を設ける
-		time.Sleep(sleepTime)
-
-		return s
-	})
-	fmt.Println("total assets", count)
-
-	return
+	return requestAll[models.EmojiReaction](ctx, a, requestUrl)
 }
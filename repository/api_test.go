@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/qiitafake"
+)
+
+// テスト用のリトライの設定, 待機時間を短くする
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	BaseDelay:         time.Millisecond,
+	MaxDelay:          10 * time.Millisecond,
+	RetryableStatuses: DefaultRetryPolicy.RetryableStatuses,
+}
+
+// フェイクサーバーに向けたQiitaAPIを生成する
+func newTestAPI(t *testing.T, server *qiitafake.Server, opts ...Option) *QiitaAPI {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]Option{
+		WithBaseURL(server.BaseURL()),
+		WithRetryPolicy(testRetryPolicy),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	return NewQiitaAPI(u.Host, "token", opts...)
+}
+
+// n件の記事のフィクスチャ
+func testArticles(n int) []models.Article {
+	articles := make([]models.Article, n)
+	for i := range articles {
+		articles[i] = models.Article{
+			ID:        fmt.Sprintf("item%03d", i),
+			Title:     fmt.Sprintf("記事%d", i),
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i),
+		}
+	}
+	return articles
+}
+
+func TestRequestArticles(t *testing.T) {
+	server := qiitafake.NewServer(qiitafake.Fixtures{Articles: testArticles(45)})
+	defer server.Close()
+	api := newTestAPI(t, server)
+
+	tests := []struct {
+		name      string
+		params    string
+		wantIDs   []string
+		wantTotal int
+	}{
+		{"1ページ目", "page=1&per_page=20", []string{"item000", "item019"}, 45},
+		{"最後のページ", "page=3&per_page=20", []string{"item040", "item044"}, 45},
+		{"範囲外のページ", "page=4&per_page=20", nil, 45},
+		{"query", "page=1&per_page=100&query=" + url.QueryEscape("created:>=2024-02-01"), []string{"item031", "item044"}, 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles, total, err := api.RequestArticles(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			var got []string
+			if len(articles) > 0 {
+				got = []string{articles[0].ID, articles[len(articles)-1].ID}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("first and last = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+// 指定したページから最後のページまで1ページずつ取得する
+func TestArticlePages(t *testing.T) {
+	server := qiitafake.NewServer(qiitafake.Fixtures{Articles: testArticles(45)})
+	defer server.Close()
+	api := newTestAPI(t, server)
+
+	tests := []struct {
+		name      string
+		page      int
+		query     string
+		wantPages []int
+		wantItems int
+	}{
+		{"1ページ目から", 1, "", []int{1, 2, 3}, 45},
+		{"途中のページから", 2, "", []int{2, 3}, 25},
+		{"範囲外のページ", 4, "", []int{4}, 0},
+		{"query", 1, "created:>=2024-02-01", []int{1}, 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager, err := api.ArticlePages(tt.page, 20, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pages []int
+			items := 0
+			for !pager.Done() {
+				page, err := pager.Next(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				pages = append(pages, page.Number)
+				items += len(page.Items)
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) || items != tt.wantItems {
+				t.Errorf("pages = %v, items = %d, want %v, %d", pages, items, tt.wantPages, tt.wantItems)
+			}
+		})
+	}
+}
+
+// Linkヘッダーがない場合はTotal-Countに達するまでpageを進める
+func TestPagerWithoutLink(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get("page"))
+		w.Header().Set("Total-Count", "3")
+		if r.URL.Query().Get("page") == "3" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id": "a"}, {"id": "b"}]`)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	api := NewQiitaAPI(u.Host, "token", WithBaseURL(server.URL), WithRetryPolicy(testRetryPolicy))
+
+	pager, err := api.ArticlePages(1, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !pager.Done() {
+		if _, err := pager.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fmt.Sprint(requested) != "[1 2]" {
+		t.Errorf("requested pages = %v, want [1 2]", requested)
+	}
+}
+
+func TestRequestAllFollowsLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		reactions int
+		wantPages int
+	}{
+		{"なし", 0, 1},
+		{"1ページ", 100, 1},
+		{"複数ページ", 250, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reactions := make([]models.EmojiReaction, tt.reactions)
+			for i := range reactions {
+				reactions[i].Name = fmt.Sprintf("emoji%d", i)
+			}
+			server := qiitafake.NewServer(qiitafake.Fixtures{
+				Articles:         testArticles(1),
+				ArticleReactions: map[string][]models.EmojiReaction{"item000": reactions},
+			})
+			defer server.Close()
+
+			got, err := newTestAPI(t, server).RequestArticleReactions("item000")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.reactions {
+				t.Errorf("len = %d, want %d", len(got), tt.reactions)
+			}
+			if tt.reactions > 0 && got[tt.reactions-1].Name != reactions[tt.reactions-1].Name {
+				t.Errorf("last = %s, want %s", got[tt.reactions-1].Name, reactions[tt.reactions-1].Name)
+			}
+			if n := len(server.Requests()); n != tt.wantPages {
+				t.Errorf("requests = %d, want %d: %v", n, tt.wantPages, server.Requests())
+			}
+		})
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   map[string]string
+	}{
+		{"", map[string]string{}},
+		{
+			`<https://example.com/api/v2/items?page=2>; rel="next", <https://example.com/api/v2/items?page=5>; rel="last"`,
+			map[string]string{"next": "https://example.com/api/v2/items?page=2", "last": "https://example.com/api/v2/items?page=5"},
+		},
+		{`<https://example.com/a>;rel=next`, map[string]string{"next": "https://example.com/a"}},
+		{`<https://example.com/a>; rel="prev first"`, map[string]string{"prev": "https://example.com/a", "first": "https://example.com/a"}},
+	}
+	for _, tt := range tests {
+		if got := parseLinkHeader(tt.header); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("parseLinkHeader(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Qiita Teamのアセットの配信元
+// <domain>/files/ はチームにアップロードされた画像や添付ファイル
+var qiitaAssetHosts = []string{
+	"qiita-image-store.s3.amazonaws.com",
+	"qiita-image-store.s3.ap-northeast-1.amazonaws.com",
+	"s3-ap-northeast-1.amazonaws.com/qiita-image-store/",
+}
+
+// ドメインに対するデフォルトのアセットの配信元
+func DefaultAssetHosts(domain string) []string {
+	hosts := slices.Clone(qiitaAssetHosts)
+	if domain != "" {
+		hosts = append([]string{domain + "/files/"}, hosts...)
+	}
+	return hosts
+}
+
+// WithAssetHosts はデフォルトの配信元に加えてアセットとして扱うホスト (allow) と、アセットとして扱わないホスト (deny) を指定する
+// 書式は ValidateAssetHosts を参照, 不正な値は無視するため、事前に ValidateAssetHosts で検証する
+func WithAssetHosts(allow, deny []string) Option {
+	return func(a *QiitaAPI) {
+		a.assetAllowHosts = allow
+		a.assetDenyHosts = deny
+	}
+}
+
+// デフォルトの配信元とWithAssetHostsの指定からアセットの抽出に使う設定を作る
+// ベースURLのホストがドメインと異なる場合 (フェイクサーバーやプロキシ) は、そのホストの /files/ も配信元とする
+func (a *QiitaAPI) buildAssetExtractor(domain string) {
+	allow := DefaultAssetHosts(domain)
+	if u, err := url.Parse(a.requestBaseApiUrl); err == nil && u.Host != "" && u.Host != domain {
+		allow = append(allow, u.Host+"/files/")
+	}
+	a.assetExtractor = newAssetExtractor(append(allow, a.assetAllowHosts...), a.assetDenyHosts)
+}
+
+// 記事本文 (Markdown) とHTMLの本文からアセット (画像や添付ファイル) のURLを抽出する
+// 同じURLが複数回現れる場合は、最初の1件のみを返す
+func (a QiitaAPI) ExtractAssetURLs(body, renderedBody string) []string {
+	return a.assetExtractor.extract(body, renderedBody)
+}
+
+// アセットのホストの指定を検証する
+// 書式は "host[:port][/path]" で、hostの先頭に "*." を付けるとサブドメインに一致する
+// pathを指定した場合は、URLのパスがpathから始まる場合のみ一致する
+//
+//	example.qiita.com/files/
+//	*.s3.amazonaws.com
+//	127.0.0.1:8080
+func ValidateAssetHosts(hosts []string) error {
+	var errs []error
+	for _, h := range hosts {
+		if _, err := parseAssetHostRule(h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// アセットのホストの指定1件
+type assetHostRule struct {
+	host       string // 小文字, "*." は除いた値
+	port       string // 空の場合は任意のポート
+	pathPrefix string
+	wildcard   bool
+}
+
+// ホスト名として使える文字
+var hostnameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+func parseAssetHostRule(s string) (assetHostRule, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return assetHostRule{}, errors.New("asset host is empty")
+	}
+	if strings.Contains(raw, "://") {
+		return assetHostRule{}, fmt.Errorf("asset host %q must not contain a scheme", s)
+	}
+
+	var r assetHostRule
+	hostport := raw
+	if i := strings.Index(raw, "/"); i >= 0 {
+		hostport, r.pathPrefix = raw[:i], raw[i:]
+	}
+	host := hostport
+	if h, port, err := net.SplitHostPort(hostport); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return assetHostRule{}, fmt.Errorf("asset host %q has an invalid port", s)
+		}
+		host, r.port = h, port
+	}
+	host = strings.ToLower(host)
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		host, r.wildcard = rest, true
+	}
+	if net.ParseIP(host) == nil && !hostnameRegexp.MatchString(host) {
+		return assetHostRule{}, fmt.Errorf("asset host %q is not a valid host name", s)
+	}
+	r.host = host
+
+	return r, nil
+}
+
+func (r assetHostRule) match(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if r.wildcard {
+		if !strings.HasSuffix(host, "."+r.host) {
+			return false
+		}
+	} else if host != r.host {
+		return false
+	}
+	if r.port != "" && u.Port() != r.port {
+		return false
+	}
+	return strings.HasPrefix(u.EscapedPath(), r.pathPrefix)
+}
+
+// 記事本文からアセットのURLを抽出する
+// denyに一致せず、allowのいずれかに一致するURLのみをアセットとして扱う
+type assetExtractor struct {
+	allow []assetHostRule
+	deny  []assetHostRule
+}
+
+// 不正なホストの指定は無視する
+func newAssetExtractor(allow, deny []string) assetExtractor {
+	var e assetExtractor
+	for _, s := range allow {
+		if r, err := parseAssetHostRule(s); err == nil {
+			e.allow = append(e.allow, r)
+		}
+	}
+	for _, s := range deny {
+		if r, err := parseAssetHostRule(s); err == nil {
+			e.deny = append(e.deny, r)
+		}
+	}
+	return e
+}
+
+func (e assetExtractor) isAsset(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	for _, r := range e.deny {
+		if r.match(u) {
+			return false
+		}
+	}
+	for _, r := range e.allow {
+		if r.match(u) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// Markdownのインラインリンク, 画像のURL: [text](url "title"), ![alt](<url>)
+	markdownLinkRegexp = regexp.MustCompile(`\]\(\s*<?(https?://[^\s<>)]+)`)
+	// Markdownのリンク参照定義: [id]: url "title"
+	markdownRefRegexp = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:\s*<?(https?://[^\s<>]+)`)
+	// 自動リンク: <url>
+	autolinkRegexp = regexp.MustCompile(`<(https?://[^\s<>]+)>`)
+	// HTMLの属性: <img src="url">, <a href="url">
+	htmlAttrRegexp = regexp.MustCompile(`(?i)\s(?:src|href|data-src|data-canonical-src|poster)\s*=\s*["']?(https?://[^"'\s<>]+)`)
+	// 本文中のURL (GitHub Flavored Markdownの拡張自動リンク)
+	bareURLRegexp = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+	// インラインコード
+	inlineCodeRegexp = regexp.MustCompile("`[^`\n]+`")
+	// HTMLのコードブロック
+	htmlCodeRegexp = regexp.MustCompile(`(?is)<pre[\s>].*?</pre>|<code[\s>].*?</code>`)
+)
+
+// Markdownの本文 (body) とHTMLの本文 (renderedBody) からアセットのURLを抽出する
+// コードブロック, インラインコード内のURLは対象外とする
+// 結果は本文中に現れた順で、同じURLは最初の1件のみを返す
+func (e assetExtractor) extract(body, renderedBody string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(spans []urlSpan) {
+		for _, sp := range spans {
+			if !seen[sp.url] && e.isAsset(sp.url) {
+				seen[sp.url] = true
+				urls = append(urls, sp.url)
+			}
+		}
+	}
+	add(markdownURLSpans(body))
+	add(htmlURLSpans(renderedBody))
+	return urls
+}
+
+// 本文中のURLの位置 (バイト単位, [start, end))
+type urlSpan struct {
+	start, end int
+	url        string
+}
+
+// Markdownの本文のURLの位置, コードブロックとインラインコード内のURLは含めない
+func markdownURLSpans(body string) []urlSpan {
+	md := inlineCodeRegexp.ReplaceAllStringFunc(stripFencedCode(body), blank)
+	return findURLs(md, markdownLinkRegexp, markdownRefRegexp, autolinkRegexp, htmlAttrRegexp, bareURLRegexp)
+}
+
+// HTMLの本文の属性のURLの位置, pre, code要素内のURLは含めない
+func htmlURLSpans(renderedBody string) []urlSpan {
+	html := htmlCodeRegexp.ReplaceAllStringFunc(renderedBody, blank)
+	return findURLs(html, htmlAttrRegexp)
+}
+
+// 正規表現の最初のグループ (グループがない場合は全体) の位置を、文字列中の位置の順に返す
+// 複数の正規表現が同じURLに一致した場合 (位置が重なる場合) は、先に指定した正規表現の結果のみを返す
+func findURLs(s string, regexps ...*regexp.Regexp) []urlSpan {
+	var spans []urlSpan
+	for _, re := range regexps {
+		for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 {
+				start, end = m[2], m[3]
+			}
+			u := s[start:end]
+			if re == bareURLRegexp {
+				// 文末の句読点やMarkdownの強調はURLに含めない
+				u = strings.TrimRight(u, ".,:;!?*_~")
+			}
+			spans = append(spans, urlSpan{start: start, end: start + len(u), url: u})
+		}
+	}
+	slices.SortStableFunc(spans, func(a, b urlSpan) int {
+		return a.start - b.start
+	})
+
+	result := spans[:0]
+	last := 0
+	for _, sp := range spans {
+		if sp.start < last {
+			continue
+		}
+		result = append(result, sp)
+		last = sp.end
+	}
+	return result
+}
+
+// フェンスで囲まれたコードブロック (``` または ~~~) を空白に置き換える
+// 位置を保つため、改行はそのまま残す
+func stripFencedCode(body string) string {
+	lines := strings.SplitAfter(body, "\n")
+	var fence string
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) <= 3 {
+			if fence == "" {
+				if f := fenceOf(trimmed); f != "" {
+					fence = f
+					lines[i] = blank(line)
+					continue
+				}
+			} else if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+				fence = ""
+				lines[i] = blank(line)
+				continue
+			}
+		}
+		if fence != "" {
+			lines[i] = blank(line)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// 行がコードブロックの開始であれば、そのフェンス (``` や ~~~~ など) を返す
+func fenceOf(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// 改行以外の文字を空白に置き換える
+// URLの位置を保つため、マルチバイト文字はバイト数分の空白にする
+func blank(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c != '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
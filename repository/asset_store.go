@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// アセットストアのインデックスのファイル名
+const AssetStoreIndexFileName = "index.json"
+
+// AssetEntry はアセットストアに保存したアセット
+type AssetEntry struct {
+	SHA256 string `json:"sha256"`
+	Path   string `json:"path"` // ストアのディレクトリからの相対パス
+	Size   int64  `json:"size"`
+}
+
+// AssetStore はアセットを内容のSHA-256をキーとして保存する共有のストア
+// 同じ内容のアセットは1つのファイルにまとめ、ダウンロード済みのURLは再度ダウンロードしない
+//
+//	<dir>/index.json               URL → AssetEntry
+//	<dir>/sha256/<先頭2文字>/<hash><拡張子>
+type AssetStore struct {
+	dir   string
+	mu    sync.Mutex
+	index map[string]AssetEntry
+	dirty bool
+}
+
+// アセットストアを開く, インデックスがない場合は空のストアとして扱う
+func OpenAssetStore(dir string) (*AssetStore, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return LoadAssetStore(dir)
+}
+
+// アセットストアのインデックスのみ読み込む, ディレクトリは作成しない (dry-run用)
+// ディレクトリやインデックスがない場合は空のストアとして扱う
+func LoadAssetStore(dir string) (*AssetStore, error) {
+	s := &AssetStore{dir: dir, index: make(map[string]AssetEntry)}
+	b, err := os.ReadFile(filepath.Join(dir, AssetStoreIndexFileName))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset store index: %w", err)
+	}
+	if err := json.Unmarshal(b, &s.index); err != nil {
+		return nil, fmt.Errorf("failed to parse asset store index: %w", err)
+	}
+
+	return s, nil
+}
+
+// ストアのディレクトリ
+func (s *AssetStore) Dir() string {
+	return s.dir
+}
+
+// ダウンロード済みのURLであれば、そのエントリを返す
+// インデックスにあってもファイルが削除されている場合は未ダウンロードとして扱う
+func (s *AssetStore) Lookup(assetURL string) (AssetEntry, bool) {
+	s.mu.Lock()
+	e, ok := s.index[assetURL]
+	s.mu.Unlock()
+	if !ok {
+		return AssetEntry{}, false
+	}
+
+	if _, err := os.Stat(s.FilePath(e)); err != nil {
+		return AssetEntry{}, false
+	}
+	return e, true
+}
+
+// エントリのファイルのパス
+func (s *AssetStore) FilePath(e AssetEntry) string {
+	return filepath.Join(s.dir, filepath.FromSlash(e.Path))
+}
+
+// インデックスを保存する
+func (s *AssetStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	indexPath := filepath.Join(s.dir, AssetStoreIndexFileName)
+	if err := os.WriteFile(indexPath+".tmp", b, 0666); err != nil {
+		return fmt.Errorf("failed to write asset store index: %w", err)
+	}
+	if err := os.Rename(indexPath+".tmp", indexPath); err != nil {
+		return fmt.Errorf("failed to write asset store index: %w", err)
+	}
+	s.dirty = false
+
+	return nil
+}
+
+// ダウンロードしたファイルを内容のハッシュのパスに移動し、インデックスに登録する
+// 同じ内容のファイルが既にある場合は、ダウンロードしたファイルを削除して既存のファイルを使う
+func (s *AssetStore) put(assetURL, tmpPath, sum string, size int64) (AssetEntry, error) {
+	ext := strings.ToLower(path.Ext(assetBaseName(assetURL)))
+	e := AssetEntry{
+		SHA256: sum,
+		Path:   path.Join("sha256", sum[:2], sum+ext),
+		Size:   size,
+	}
+
+	dst := s.FilePath(e)
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return AssetEntry{}, err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		os.Remove(tmpPath)
+	} else if err := os.Rename(tmpPath, dst); err != nil {
+		return AssetEntry{}, err
+	}
+
+	s.mu.Lock()
+	s.index[assetURL] = e
+	s.dirty = true
+	s.mu.Unlock()
+
+	return e, nil
+}
+
+// アセットをストアにダウンロードする, ダウンロード済みのURLはリクエストせずにエントリを返す
+func (a QiitaAPI) DownloadAssetToStoreContext(ctx context.Context, store *AssetStore, assetURL string) (AssetEntry, error) {
+	if e, ok := store.Lookup(assetURL); ok {
+		return e, nil
+	}
+
+	tmp, err := os.CreateTemp(store.dir, ".download-*")
+	if err != nil {
+		return AssetEntry{}, err
+	}
+	tmp.Close()
+
+	sum, size, err := a.downloadToFile(ctx, assetURL, tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return AssetEntry{}, err
+	}
+
+	return store.put(assetURL, tmp.Name(), sum, size)
+}
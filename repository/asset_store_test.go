@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+func newTestAssetServer(t *testing.T) *qiitafake.Server {
+	t.Helper()
+	server := qiitafake.NewServer(qiitafake.Fixtures{
+		Assets: map[string]qiitafake.Asset{
+			"/files/a.png":    {ContentType: "image/png", Body: testPNG},
+			"/files/copy.png": {ContentType: "image/png", Body: testPNG},
+			"/files/html.png": {ContentType: "text/html", Body: []byte("<!DOCTYPE html><html>login</html>")},
+		},
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+// 同じ内容のアセットは1つのファイルにまとめ、ダウンロード済みのURLはリクエストしない
+func TestAssetStoreDedup(t *testing.T) {
+	server := newTestAssetServer(t)
+	api := newTestAPI(t, server)
+	store, err := OpenAssetStore(filepath.Join(t.TempDir(), "_assets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	a, err := api.DownloadAssetToStoreContext(ctx, store, server.URL+"/files/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := api.DownloadAssetToStoreContext(ctx, store, server.URL+"/files/copy.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != copied {
+		t.Errorf("entries = %+v, %+v, want the same entry", a, copied)
+	}
+	if want := "sha256/" + a.SHA256[:2] + "/" + a.SHA256 + ".png"; a.Path != want || a.Size != int64(len(testPNG)) {
+		t.Errorf("entry = %+v, want path %s", a, want)
+	}
+	if b, err := os.ReadFile(store.FilePath(a)); err != nil || string(b) != string(testPNG) {
+		t.Errorf("stored file = %q, %v", b, err)
+	}
+
+	sent := len(server.Requests())
+	if _, err := api.DownloadAssetToStoreContext(ctx, store, server.URL+"/files/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests()) - sent; n != 0 {
+		t.Errorf("requests for a downloaded asset = %d, want 0", n)
+	}
+}
+
+// 保存したインデックスを開き直すと、ダウンロード済みのURLを引き継ぐ
+func TestAssetStoreReload(t *testing.T) {
+	server := newTestAssetServer(t)
+	api := newTestAPI(t, server)
+	dir := filepath.Join(t.TempDir(), "_assets")
+	store, err := OpenAssetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetURL := server.URL + "/files/a.png"
+	e, err := api.DownloadAssetToStoreContext(context.Background(), store, assetURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, AssetStoreIndexFileName+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary index remains: %v", err)
+	}
+
+	reopened, err := OpenAssetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Lookup(assetURL); !ok || got != e {
+		t.Errorf("Lookup() = %+v, %v, want %+v", got, ok, e)
+	}
+
+	// インデックスにあってもファイルが削除された場合は未ダウンロードとして扱う
+	if err := os.Remove(reopened.FilePath(e)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Lookup(assetURL); ok {
+		t.Error("Lookup() finds a deleted file")
+	}
+}
+
+// 検証に失敗したアセットは一時ファイルを削除し、インデックスに登録しない
+func TestAssetStoreDownloadFailure(t *testing.T) {
+	server := newTestAssetServer(t)
+	api := newTestAPI(t, server)
+	dir := filepath.Join(t.TempDir(), "_assets")
+	store, err := OpenAssetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{server.URL + "/files/html.png", server.URL + "/files/missing.png"} {
+		if _, err := api.DownloadAssetToStoreContext(context.Background(), store, s); err == nil {
+			t.Errorf("%s: download succeeded", s)
+		}
+		if _, ok := store.Lookup(s); ok {
+			t.Errorf("%s: failed asset is registered", s)
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			t.Errorf("file remains: %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, AssetStoreIndexFileName)); !os.IsNotExist(err) {
+		t.Errorf("index is written without changes: %v", err)
+	}
+}
+
+// ディレクトリがない場合、LoadAssetStore は作成せずに空のストアを返す
+func TestLoadAssetStoreMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "_assets")
+	store, err := LoadAssetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Lookup("https://example.qiita.com/files/a.png"); ok {
+		t.Error("empty store finds an asset")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("store dir is created: %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, AssetStoreIndexFileName), []byte("{"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAssetStore(dir); err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Errorf("LoadAssetStore() with a broken index = %v", err)
+	}
+}
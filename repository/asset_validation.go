@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidAsset はダウンロードしたアセットの内容が不正な場合のエラー
+// (Content-Lengthと実際のサイズの不一致, 拡張子と内容の不一致など)
+var ErrInvalidAsset = errors.New("invalid asset")
+
+// 内容を判定するために読み込む先頭のバイト数 (http.DetectContentTypeが参照する最大のサイズ)
+const sniffLen = 512
+
+// 拡張子ごとに許容するContent-Type
+// ここにない拡張子はContent-Typeを検証しない (HTMLのエラーページでないことのみ確認する)
+var assetExtContentTypes = map[string][]string{
+	".png":  {"image/png"},
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".bmp":  {"image/bmp"},
+	".ico":  {"image/x-icon", "image/vnd.microsoft.icon"},
+	".pdf":  {"application/pdf"},
+	".zip":  {"application/zip", "application/x-zip-compressed"},
+	".gz":   {"application/gzip", "application/x-gzip"},
+	".mp4":  {"video/mp4"},
+	".svg":  {"image/svg+xml", "text/xml", "application/xml", "text/plain"},
+}
+
+// ステータスコードが2xxでない場合はエラーを返す
+func checkAssetStatus(res *http.Response) error {
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return newAPIError(res)
+	}
+	return nil
+}
+
+// 受信したサイズがContent-Lengthと一致するか検証する
+// Content-Lengthが不明な場合 (chunked, gzipの自動展開など) は検証しない
+func checkAssetLength(res *http.Response, size int64) error {
+	if res.ContentLength >= 0 && size != res.ContentLength {
+		return fmt.Errorf("%w: Content-Length is %d but received %d bytes", ErrInvalidAsset, res.ContentLength, size)
+	}
+	return nil
+}
+
+// 拡張子とContent-Type, ファイル先頭のバイト列 (マジックバイト) が一致するか検証する
+// 画像の拡張子でHTMLのエラーページが返された場合などをエラーにする
+func checkAssetContent(ext, contentType string, head []byte) error {
+	ext = strings.ToLower(ext)
+	if ext == ".html" || ext == ".htm" {
+		return nil
+	}
+
+	headerType, _, _ := mime.ParseMediaType(contentType)
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if headerType == "text/html" || sniffed == "text/html" {
+		return fmt.Errorf("%w: received HTML for %q asset (Content-Type: %s)", ErrInvalidAsset, ext, contentType)
+	}
+
+	allowed, ok := assetExtContentTypes[ext]
+	if !ok {
+		return nil
+	}
+	// Content-Typeはapplication/octet-streamなど汎用的な値のこともあるため、マジックバイトで判定する
+	if !slices.Contains(allowed, sniffed) {
+		return fmt.Errorf("%w: content of %q asset looks like %s (Content-Type: %s)", ErrInvalidAsset, ext, sniffed, contentType)
+	}
+	return nil
+}
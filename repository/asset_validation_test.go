@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+func TestCheckAssetContent(t *testing.T) {
+	html := []byte("<!DOCTYPE html><html><body>ログイン</body></html>")
+	tests := []struct {
+		name        string
+		ext         string
+		contentType string
+		head        []byte
+		wantErr     bool
+	}{
+		{"PNG", ".png", "image/png", testPNG, false},
+		{"拡張子の大文字小文字は区別しない", ".PNG", "image/png", testPNG, false},
+		{"汎用のContent-TypeでもマジックバイトがPNG", ".png", "application/octet-stream", testPNG, false},
+		{"PNGとしてHTMLが返された", ".png", "text/html; charset=utf-8", html, true},
+		{"Content-TypeがなくてもHTMLの内容", ".png", "", html, true},
+		{"Content-TypeのみHTML", ".png", "text/html", testPNG, true},
+		{"拡張子と内容の不一致", ".jpg", "image/jpeg", testPNG, true},
+		{"検証しない拡張子", ".txt", "text/plain", []byte("memo"), false},
+		{"検証しない拡張子でもHTMLはエラー", ".txt", "text/html", html, true},
+		{"HTMLの拡張子", ".html", "text/html", html, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAssetContent(tt.ext, tt.contentType, tt.head)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkAssetContent() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAsset) {
+				t.Errorf("err = %v, want ErrInvalidAsset", err)
+			}
+		})
+	}
+}
+
+func TestCheckAssetLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength int64
+		size          int64
+		wantErr       bool
+	}{
+		{"一致", 100, 100, false},
+		{"途中で切断された", 100, 60, true},
+		{"Content-Lengthより多い", 100, 120, true},
+		{"Content-Lengthが不明", -1, 60, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAssetLength(&http.Response{ContentLength: tt.contentLength}, tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkAssetLength() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAsset) {
+				t.Errorf("err = %v, want ErrInvalidAsset", err)
+			}
+		})
+	}
+}
+
+func TestCheckAssetStatus(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusForbidden, http.StatusInternalServerError} {
+		err := checkAssetStatus(testErrorResponse(status, nil, ""))
+		if wantErr := status >= 300; (err != nil) != wantErr {
+			t.Errorf("status %d: checkAssetStatus() = %v, wantErr %v", status, err, wantErr)
+		}
+		if err != nil && StatusCode(err) != status {
+			t.Errorf("status %d: StatusCode() = %d", status, StatusCode(err))
+		}
+	}
+}
+
+// 検証に失敗した場合は保存先にファイルを作成せず、一時ファイルも残さない
+func TestDownloadAssetValidation(t *testing.T) {
+	server := qiitafake.NewServer(qiitafake.Fixtures{
+		Assets: map[string]qiitafake.Asset{
+			"/files/a.png":    {ContentType: "image/png", Body: testPNG},
+			"/files/html.png": {ContentType: "text/html", Body: []byte("<html>login</html>")},
+		},
+	})
+	defer server.Close()
+	api := newTestAPI(t, server)
+
+	tests := []struct {
+		path       string
+		wantErr    bool
+		wantStatus int
+	}{
+		{"/files/a.png", false, 0},
+		{"/files/html.png", true, 0},
+		{"/files/missing.png", true, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			dir := t.TempDir()
+			localPath := filepath.Join(dir, "asset.png")
+			err := api.DownloadAsset(server.URL+tt.path, localPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DownloadAsset() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := StatusCode(err); got != tt.wantStatus {
+				t.Errorf("StatusCode() = %d, want %d", got, tt.wantStatus)
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if tt.wantErr && len(entries) > 0 {
+				t.Errorf("files remain after failure: %v", entries)
+			}
+			if !tt.wantErr {
+				if info, err := os.Stat(localPath); err != nil || info.Mode().Perm() != 0644 || len(entries) != 1 {
+					t.Errorf("saved file = %v, %v, entries = %v", info, err, entries)
+				}
+			}
+		})
+	}
+}
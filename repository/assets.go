@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AssetResult はアセット1件のダウンロード結果
+type AssetResult struct {
+	URL       string
+	LocalPath string // 保存したファイルのパス, 失敗した場合は空
+	Err       error
+}
+
+// ダウンロードに成功したアセットのURLを、記事のディレクトリからの相対パスに置換する (Markdownの本文)
+// 失敗したアセットは元のURLのまま残す
+// コードブロック, インラインコード内のURLは置換しない
+func RewriteAssetURLs(body, artDir string, results []AssetResult) string {
+	return ReplaceAssetURLs(body, assetRelPaths(artDir, results))
+}
+
+// RewriteAssetURLs のHTMLの本文 (rendered_body) 版
+// pre, code要素内のURLは置換しない
+func RewriteRenderedAssetURLs(renderedBody, artDir string, results []AssetResult) string {
+	return replaceSpans(renderedBody, htmlURLSpans(renderedBody), assetRelPaths(artDir, results))
+}
+
+// ダウンロードに成功したアセットのURL → 記事のディレクトリからの相対パス
+func assetRelPaths(artDir string, results []AssetResult) map[string]string {
+	paths := make(map[string]string, len(results))
+	for _, r := range results {
+		if r.Err != nil || r.LocalPath == "" {
+			continue
+		}
+		rel, err := filepath.Rel(artDir, r.LocalPath)
+		if err != nil {
+			continue
+		}
+		paths[r.URL] = filepath.ToSlash(rel)
+	}
+	return paths
+}
+
+// Markdownの本文のアセットのURLを、pathsで指定したパス (スラッシュ区切り) に置換する
+// アセットの抽出と同じ位置のURLのみ置換し、コードブロック, インラインコード内のURLはそのまま残す
+func ReplaceAssetURLs(body string, paths map[string]string) string {
+	return replaceSpans(body, markdownURLSpans(body), paths)
+}
+
+// pathsに含まれるURLの位置のみ置換する
+func replaceSpans(body string, spans []urlSpan, paths map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		p, ok := paths[sp.url]
+		if !ok {
+			continue
+		}
+		b.WriteString(body[last:sp.start])
+		b.WriteString(escapeAssetPath(p))
+		last = sp.end
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// Markdownのリンクとして認識されない文字をパーセントエンコーディングする
+var assetPathEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E", `"`, "%22")
+
+func escapeAssetPath(p string) string {
+	return assetPathEscaper.Replace(p)
+}
+
+// アセットのURLから、記事のディレクトリに保存するファイル名を決める
+// ファイル名はURLのパスの末尾を使い、同じファイル名になる別のURLにはURLのハッシュを付与して区別する
+// 結果はURLの順序のみに依存するため、再開時や同期時にも同じファイル名になる
+func AssetFileNames(urls []string) map[string]string {
+	names := make(map[string]string, len(urls))
+	used := make(map[string]bool, len(urls))
+	for _, s := range urls {
+		if _, ok := names[s]; ok {
+			continue
+		}
+
+		name := assetBaseName(s)
+		if used[name] {
+			name = assetHashedName(name, s)
+		}
+		used[name] = true
+		names[s] = name
+	}
+	return names
+}
+
+// ファイル名にURLのハッシュを付与する
+func assetHashedName(name, assetURL string) string {
+	ext := path.Ext(name)
+	sum := sha256.Sum256([]byte(assetURL))
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), hex.EncodeToString(sum[:4]), ext)
+}
+
+// 記事のディレクトリにダウンロード済みのアセットのパスを探す
+// ファイル名が衝突した場合のURLのハッシュ付きのファイル名を優先する
+func FindAssetFile(artDir, assetURL string) (string, bool) {
+	name := assetBaseName(assetURL)
+	for _, candidate := range []string{assetHashedName(name, assetURL), name} {
+		p := filepath.Join(artDir, candidate)
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// ファイル名として使用できない文字
+var invalidFileNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+
+// URLのパスの末尾をファイル名として使える形にする (クエリ文字列は含めない)
+func assetBaseName(assetURL string) string {
+	name := path.Base(assetURL)
+	if u, err := url.Parse(assetURL); err == nil {
+		name = path.Base(u.Path)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+	name = invalidFileNameReplacer.Replace(name)
+	if name == "" || name == "." || name == "_" {
+		name = "asset"
+	}
+	return name
+}
+
+// アセットのリクエストを作成する
+// アクセストークンはAPIとチームのドメインのアセットにのみ付与し、S3や asset_allow_hosts の他のホストには送らない
+func (a QiitaAPI) newAssetRequest(ctx context.Context, assetURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, a.wrapError(err)
+	}
+	if a.isAuthHost(req.URL) {
+		req.Header.Set("Authorization", a.authHeaderToken)
+	}
+	if a.userAgent != "" {
+		req.Header.Set("User-Agent", a.userAgent)
+	}
+	RequestCount.Add(1)
+	return req, nil
+}
+
+// アセットを1件ダウンロードし、filePathに保存する
+func (a QiitaAPI) DownloadAsset(assetURL, filePath string) error {
+	return a.DownloadAssetContext(context.Background(), assetURL, filePath)
+}
+
+// DownloadAssetのcontext.Contextを受け取る版
+// キャンセルされた場合など、ダウンロードに失敗した場合は書きかけのファイルを削除する
+func (a QiitaAPI) DownloadAssetContext(ctx context.Context, assetURL, filePath string) error {
+	_, _, err := a.downloadToFile(ctx, assetURL, filePath)
+	return err
+}
+
+// アセットをダウンロードしてfilePathに保存し、内容のSHA-256とサイズを返す
+// 同じディレクトリの一時ファイルに書き込み、検証に成功した場合のみfilePathにリネームする
+// 2xx以外のステータス, Content-Lengthとの不一致, 拡張子と内容の不一致はエラーにする
+func (a QiitaAPI) downloadToFile(ctx context.Context, assetURL, filePath string) (_ string, _ int64, retErr error) {
+	res, err := a.doWithRetry(ctx, assetURL, a.newAssetRequest)
+	if err != nil {
+		return "", 0, err
+	}
+	defer res.Body.Close()
+
+	if err := checkAssetStatus(res); err != nil {
+		return "", 0, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		f.Close()
+		if retErr != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	// 先頭のバイト列で内容を判定するため、一時ファイルへの書き込みと並行して保持しておく
+	h := sha256.New()
+	head := &headBuffer{limit: sniffLen}
+	size, err := io.Copy(io.MultiWriter(f, h, head), res.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := checkAssetLength(res, size); err != nil {
+		return "", 0, err
+	}
+	if err := checkAssetContent(path.Ext(assetBaseName(assetURL)), res.Header.Get("Content-Type"), head.buf); err != nil {
+		return "", 0, err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	// os.CreateTempは0600で作成するため、通常のファイルと同じ権限に戻す
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(f.Name(), filePath); err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// 書き込まれたバイト列の先頭limitバイトのみを保持する
+type headBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - len(b.buf); n > 0 {
+		b.buf = append(b.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
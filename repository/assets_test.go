@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+// PNGとして判定される内容
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestExtractAssetURLs(t *testing.T) {
+	e := newAssetExtractor(DefaultAssetHosts("example.qiita.com"), []string{"example.qiita.com/files/private/"})
+	tests := []struct {
+		name         string
+		body         string
+		renderedBody string
+		want         []string
+	}{
+		{
+			name: "画像とリンク",
+			body: "![a](https://example.qiita.com/files/a.png)\n[b](<https://qiita-image-store.s3.amazonaws.com/0/1/b.pdf> \"title\")",
+			want: []string{"https://example.qiita.com/files/a.png", "https://qiita-image-store.s3.amazonaws.com/0/1/b.pdf"},
+		},
+		{
+			name: "重複と対象外のホスト",
+			body: "https://example.qiita.com/files/a.png https://example.com/x.png https://example.qiita.com/files/a.png.",
+			want: []string{"https://example.qiita.com/files/a.png"},
+		},
+		{
+			name: "コードブロックとインラインコード",
+			body: "```\nhttps://example.qiita.com/files/code.png\n```\n`https://example.qiita.com/files/inline.png` 日本語 https://example.qiita.com/files/c.png",
+			want: []string{"https://example.qiita.com/files/c.png"},
+		},
+		{
+			name: "deny",
+			body: "https://example.qiita.com/files/private/a.png https://example.qiita.com/files/b.png",
+			want: []string{"https://example.qiita.com/files/b.png"},
+		},
+		{
+			name:         "HTMLの属性",
+			renderedBody: `<img src="https://example.qiita.com/files/a.png"><pre><code>https://example.qiita.com/files/code.png</code></pre>`,
+			want:         []string{"https://example.qiita.com/files/a.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.extract(tt.body, tt.renderedBody); !slices.Equal(got, tt.want) {
+				t.Errorf("extract() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteAssetURLs(t *testing.T) {
+	artDir := filepath.Join("out", "group", "item")
+	results := []AssetResult{
+		{URL: "https://example.qiita.com/files/a.png", LocalPath: filepath.Join(artDir, "a.png")},
+		{URL: "https://example.qiita.com/files/a.png.1", LocalPath: filepath.Join(artDir, "a (1).png")},
+		{URL: "https://example.qiita.com/files/b.png", Err: os.ErrNotExist},
+	}
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "画像",
+			body: "日本語 ![a](https://example.qiita.com/files/a.png)",
+			want: "日本語 ![a](a.png)",
+		},
+		{
+			name: "前方一致する別のURL",
+			body: "https://example.qiita.com/files/a.png.1 https://example.qiita.com/files/a.png",
+			want: "a%20%281%29.png a.png",
+		},
+		{
+			name: "コード内は置換しない",
+			body: "```\n![a](https://example.qiita.com/files/a.png)\n```\n`https://example.qiita.com/files/a.png`\n![a](https://example.qiita.com/files/a.png)",
+			want: "```\n![a](https://example.qiita.com/files/a.png)\n```\n`https://example.qiita.com/files/a.png`\n![a](a.png)",
+		},
+		{
+			name: "失敗したアセット",
+			body: "![b](https://example.qiita.com/files/b.png)",
+			want: "![b](https://example.qiita.com/files/b.png)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteAssetURLs(tt.body, artDir, results); got != tt.want {
+				t.Errorf("RewriteAssetURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteRenderedAssetURLs(t *testing.T) {
+	artDir := filepath.Join("out", "group", "item")
+	results := []AssetResult{
+		{URL: "https://example.qiita.com/files/a.png", LocalPath: filepath.Join(artDir, "a.png")},
+		{URL: "https://example.qiita.com/files/b.png", Err: os.ErrNotExist},
+	}
+	body := `<img src="https://example.qiita.com/files/a.png"><img src="https://example.qiita.com/files/b.png"><code>https://example.qiita.com/files/a.png</code>`
+	want := `<img src="a.png"><img src="https://example.qiita.com/files/b.png"><code>https://example.qiita.com/files/a.png</code>`
+	if got := RewriteRenderedAssetURLs(body, artDir, results); got != want {
+		t.Errorf("RewriteRenderedAssetURLs() = %q, want %q", got, want)
+	}
+}
+
+func TestAssetFileNames(t *testing.T) {
+	urls := []string{
+		"https://example.qiita.com/files/a.png",
+		"https://qiita-image-store.s3.amazonaws.com/0/1/a.png",
+		"https://example.qiita.com/files/%E7%94%BB%E5%83%8F.png?w=100",
+		"https://example.qiita.com/files/",
+	}
+	names := AssetFileNames(urls)
+	// 同じファイル名になる2件目はURLのハッシュを付与する
+	want := []string{"a.png", "a-f0ef63c4.png", "画像.png", "files"}
+	for i, u := range urls {
+		if names[u] != want[i] {
+			t.Errorf("name of %s = %q, want %q", u, names[u], want[i])
+		}
+	}
+}
+
+// アクセストークンはAPIとチームのドメインにのみ送信する
+func TestDownloadAssetAuthorization(t *testing.T) {
+	server := qiitafake.NewServer(qiitafake.Fixtures{
+		Assets: map[string]qiitafake.Asset{"/files/a.png": {ContentType: "image/png", Body: testPNG}},
+	})
+	defer server.Close()
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testPNG)
+	}))
+	defer other.Close()
+
+	// ホストごとに送信したAuthorizationヘッダーを記録する
+	auth := make(map[string]string)
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			auth[req.URL.Host] = req.Header.Get("Authorization")
+			return next.RoundTrip(req)
+		})
+	}
+	api := newTestAPI(t, server, WithMiddleware(record))
+	dir := t.TempDir()
+	for _, s := range []string{server.URL + "/files/a.png", other.URL + "/b.png"} {
+		localPath := filepath.Join(dir, path.Base(s))
+		if err := api.DownloadAsset(s, localPath); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(localPath); err != nil || string(b) != string(testPNG) {
+			t.Errorf("%s: content = %q, %v", s, b, err)
+		}
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	otherURL, _ := url.Parse(other.URL)
+	if got := auth[serverURL.Host]; got != "Bearer token" {
+		t.Errorf("Authorization to the team domain = %q, want the token", got)
+	}
+	if got, ok := auth[otherURL.Host]; !ok || got != "" {
+		t.Errorf("Authorization to another host = %q (requested=%v), want none", got, ok)
+	}
+}
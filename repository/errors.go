@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// エラーレスポンスのボディとして読み込む最大のサイズ
+const maxErrorBodySize = 64 * 1024
+
+// APIError はQiita APIが200以外のステータスを返した場合のエラー
+// Qiita APIのエラーレスポンスは {"message": "...", "type": "..."} の形式
+// https://qiita.com/api/v2/docs#%E3%82%A8%E3%83%A9%E3%83%BC%E3%83%AC%E3%82%B9%E3%83%9D%E3%83%B3%E3%82%B9
+type APIError struct {
+	StatusCode   int
+	Status       string
+	Method       string
+	Endpoint     string // リクエストしたURL
+	RequestID    string // X-Request-Idヘッダーの値
+	Message      string // エラーレスポンスのmessage
+	Type         string // エラーレスポンスのtype (例: not_found, unauthorized, rate_limit_exceeded)
+	RequestCount int64  // エラー発生時点の合計リクエスト数
+	// Rate-Remainingヘッダーが0の403の場合はtrue (エラーレスポンスのtypeがない場合もレート制限として扱う)
+	RateLimitExceeded bool
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.Endpoint, e.Status)
+	if e.Type != "" || e.Message != "" {
+		msg += fmt.Sprintf(" (type=%s, message=%s)", e.Type, e.Message)
+	}
+	if e.RequestID != "" {
+		msg += ", request_id=" + e.RequestID
+	}
+	return fmt.Sprintf("合計リクエスト数: %d, エラー: %s", e.RequestCount, msg)
+}
+
+// レスポンスからAPIErrorを生成する
+// ボディがQiitaのエラー形式でない場合、MessageとTypeは空のままにする
+func newAPIError(res *http.Response) *APIError {
+	e := &APIError{
+		StatusCode:        res.StatusCode,
+		Status:            res.Status,
+		RequestID:         res.Header.Get("X-Request-Id"),
+		RequestCount:      RequestCount.Load(),
+		RateLimitExceeded: isRateLimitedResponse(res),
+	}
+	if res.Request != nil {
+		e.Method = res.Request.Method
+		e.Endpoint = res.Request.URL.String()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if err == nil {
+		var qiitaErr struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		}
+		if json.Unmarshal(body, &qiitaErr) == nil {
+			e.Message = qiitaErr.Message
+			e.Type = qiitaErr.Type
+		}
+	}
+
+	return e
+}
+
+// errがAPIErrorの場合、そのステータスコードを返す, それ以外の場合は0を返す
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// 記事やコメントが存在しない (削除された) ことによるエラーかどうか
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// アクセストークンが不正, または期限切れによるエラーかどうか
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+// 権限がないことによるエラーかどうか (レート制限による403は含まない)
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden && !IsRateLimited(err)
+}
+
+// レート制限によるエラーかどうか
+// Qiita APIはレート制限を超えた場合に403 (type=rate_limit_exceeded, Rate-Remaining: 0) を返すため、429と合わせて判定する
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.Type == "rate_limit_exceeded" || apiErr.RateLimitExceeded
+}
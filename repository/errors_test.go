@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testErrorResponse(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    httptest.NewRequest(http.MethodGet, "https://example.qiita.com/api/v2/items", nil),
+	}
+}
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		header       http.Header
+		body         string
+		wantType     string
+		notFound     bool
+		unauthorized bool
+		forbidden    bool
+		rateLimited  bool
+	}{
+		{
+			name:     "404",
+			status:   http.StatusNotFound,
+			body:     `{"message": "Not found", "type": "not_found"}`,
+			wantType: "not_found",
+			notFound: true,
+		},
+		{
+			name:         "401",
+			status:       http.StatusUnauthorized,
+			body:         `{"message": "Unauthorized", "type": "unauthorized"}`,
+			wantType:     "unauthorized",
+			unauthorized: true,
+		},
+		{
+			name:      "権限のない403",
+			status:    http.StatusForbidden,
+			header:    http.Header{"Rate-Remaining": {"998"}},
+			body:      `{"message": "Forbidden", "type": "forbidden"}`,
+			wantType:  "forbidden",
+			forbidden: true,
+		},
+		{
+			name:        "typeがrate_limit_exceededの403",
+			status:      http.StatusForbidden,
+			body:        `{"message": "Rate limit exceeded", "type": "rate_limit_exceeded"}`,
+			wantType:    "rate_limit_exceeded",
+			rateLimited: true,
+		},
+		{
+			name:        "Rate-Remainingが0の403",
+			status:      http.StatusForbidden,
+			header:      http.Header{"Rate-Remaining": {"0"}},
+			body:        `<html>Forbidden</html>`,
+			rateLimited: true,
+		},
+		{
+			name:        "429",
+			status:      http.StatusTooManyRequests,
+			rateLimited: true,
+		},
+		{
+			name:   "Qiitaのエラー形式でない500",
+			status: http.StatusInternalServerError,
+			body:   "internal error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := newAPIError(testErrorResponse(tt.status, tt.header, tt.body))
+			if apiErr.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", apiErr.Type, tt.wantType)
+			}
+
+			// ラップされたエラーも判定できる
+			err := fmt.Errorf("failed to get comments: %w", apiErr)
+			if got := StatusCode(err); got != tt.status {
+				t.Errorf("StatusCode() = %d, want %d", got, tt.status)
+			}
+			if got := IsNotFound(err); got != tt.notFound {
+				t.Errorf("IsNotFound() = %v, want %v", got, tt.notFound)
+			}
+			if got := IsUnauthorized(err); got != tt.unauthorized {
+				t.Errorf("IsUnauthorized() = %v, want %v", got, tt.unauthorized)
+			}
+			if got := IsForbidden(err); got != tt.forbidden {
+				t.Errorf("IsForbidden() = %v, want %v", got, tt.forbidden)
+			}
+			if got := IsRateLimited(err); got != tt.rateLimited {
+				t.Errorf("IsRateLimited() = %v, want %v", got, tt.rateLimited)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	header := http.Header{"X-Request-Id": {"req-1"}}
+	err := newAPIError(testErrorResponse(http.StatusNotFound, header, `{"message": "Not found", "type": "not_found"}`))
+	want := "GET https://example.qiita.com/api/v2/items: 404 Not Found (type=not_found, message=Not found), request_id=req-1"
+	if !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("Error() = %q, want suffix %q", err.Error(), want)
+	}
+}
+
+// APIError以外のエラーはどの判定にも該当しない
+func TestAPIErrorOther(t *testing.T) {
+	err := errors.New("connection refused")
+	if StatusCode(err) != 0 || IsNotFound(err) || IsUnauthorized(err) || IsForbidden(err) || IsRateLimited(err) {
+		t.Errorf("non-API error is classified: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/qiita_export/models"
+)
+
+// ExportedArticle はエクスポート先のディレクトリに保存済みの記事
+type ExportedArticle struct {
+	Dir          string // <exportDir>/<group>/<id>
+	MetadataPath string
+	Article      *models.Article
+}
+
+// エクスポートした記事のMarkdownのパス
+func (e ExportedArticle) MarkdownPath() string {
+	return strings.TrimSuffix(e.MetadataPath, "_metadata.json") + ".md"
+}
+
+// エクスポート先のディレクトリの <group>/<id>/*_metadata.json を読み込む
+// 結果はメタデータのパスの順に並べる
+func LoadExportedArticles(exportDir string) ([]ExportedArticle, error) {
+	paths, err := filepath.Glob(filepath.Join(exportDir, "*", "*", "*_metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	repo := ArticleMetadata{}
+	articles := make([]ExportedArticle, 0, len(paths))
+	for _, path := range paths {
+		article, err := repo.GetArticle(path)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, ExportedArticle{
+			Dir:          filepath.Dir(path),
+			MetadataPath: path,
+			Article:      article,
+		})
+	}
+
+	return articles, nil
+}
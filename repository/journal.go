@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 出力ディレクトリに保存するジャーナルのファイル名
+const JournalFileName = ".export_journal.jsonl"
+
+// ジャーナルに記録する処理の種類
+const (
+	JournalPage     = "page"     // 記事一覧の1ページ分の処理, キーはクエリパラメータ
+	JournalArticle  = "article"  // 記事1件分の処理 (メタデータ, Markdown, アセット), キーは記事ID
+	JournalComments = "comments" // コメントと絵文字リアクションの保存, キーは記事ID
+	JournalAsset    = "asset"    // アセット1件のダウンロード, キーは "記事ID URL"
+)
+
+// ジャーナルに記録する処理の状態
+const (
+	JournalStatusDone   = "done"
+	JournalStatusFailed = "failed"
+)
+
+// JournalEntry はジャーナルの1行
+type JournalEntry struct {
+	Kind   string    `json:"kind"`
+	Key    string    `json:"key"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Journal はエクスポートの進捗を記録し、中断したエクスポートを再開するためのチェックポイント
+// 1行1エントリのJSON Linesで追記し、書き込み毎にfsyncするため、クラッシュしても直前までの記録が残る
+type Journal struct {
+	mu     sync.Mutex
+	f      *os.File
+	status map[string]JournalEntry // kind + key → 最新のエントリ
+}
+
+// 出力ディレクトリのジャーナルを開く
+// resumeがtrueの場合は既存の記録を読み込み、falseの場合は記録を破棄して新しく開始する
+func OpenJournal(outputDir string, resume bool) (*Journal, error) {
+	if err := os.MkdirAll(outputDir, 0777); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(outputDir, JournalFileName)
+	j := &Journal{status: make(map[string]JournalEntry)}
+
+	if resume {
+		if err := j.load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.f = f
+
+	return j, nil
+}
+
+func (j *Journal) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e JournalEntry
+		// 書き込み途中でクラッシュした最終行は読み飛ばす
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		j.status[journalKey(e.Kind, e.Key)] = e
+	}
+
+	return scanner.Err()
+}
+
+// 処理が完了済みかどうか
+func (j *Journal) Done(kind, key string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status[journalKey(kind, key)].Status == JournalStatusDone
+}
+
+// 処理の完了を記録する
+func (j *Journal) MarkDone(kind, key string) error {
+	return j.append(JournalEntry{Kind: kind, Key: key, Status: JournalStatusDone})
+}
+
+// 処理の失敗を記録する
+// 再開時には完了していない処理として扱われ、再実行される
+func (j *Journal) MarkFailed(kind, key string, cause error) error {
+	e := JournalEntry{Kind: kind, Key: key, Status: JournalStatusFailed}
+	if cause != nil {
+		e.Error = cause.Error()
+	}
+	return j.append(e)
+}
+
+// 最後の記録が失敗になっている処理のキーを取得する
+func (j *Journal) Failed(kind string) []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var keys []string
+	for _, e := range j.status {
+		if e.Kind == kind && e.Status == JournalStatusFailed {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+func (j *Journal) append(e JournalEntry) error {
+	e.At = time.Now()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	j.status[journalKey(e.Kind, e.Key)] = e
+
+	return nil
+}
+
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+// アセットのジャーナルのキー
+func AssetJournalKey(articleID, assetURL string) string {
+	return articleID + " " + assetURL
+}
+
+func journalKey(kind, key string) string {
+	return kind + "\x00" + key
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option はQiitaAPIの生成時に設定を変更する関数
+type Option func(*QiitaAPI)
+
+// Middleware はhttp.RoundTripperをラップし、リクエスト/レスポンスに処理を挟むための関数
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc は関数をhttp.RoundTripperとして扱うための型
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithHTTPClient はリクエストに利用するhttp.Clientを指定する
+// 未指定の場合はhttp.DefaultClientを利用する
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *QiitaAPI) {
+		if client != nil {
+			a.client = client
+		}
+	}
+}
+
+// WithScheme はAPIのスキームを指定する (例: "http")
+// WithBaseURLが指定されている場合はそちらが優先される
+func WithScheme(scheme string) Option {
+	return func(a *QiitaAPI) {
+		if scheme != "" {
+			a.scheme = scheme
+		}
+	}
+}
+
+// WithBaseURL はAPIのベースURLを指定する (例: "http://127.0.0.1:8080/api/v2")
+// httptest.Serverやプロキシ、セルフホストのミラーに向ける場合に利用する
+func WithBaseURL(baseURL string) Option {
+	return func(a *QiitaAPI) {
+		if baseURL != "" {
+			a.requestBaseApiUrl = strings.TrimSuffix(baseURL, "/")
+		}
+	}
+}
+
+// WithLogger はリトライやレート制限による待機のログを出力するロガーを指定する
+// 未指定の場合はslog.Default()を利用する
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *QiitaAPI) {
+		if logger != nil {
+			a.logger = logger
+		}
+	}
+}
+
+// WithUserAgent はリクエストに付与するUser-Agentを指定する
+func WithUserAgent(userAgent string) Option {
+	return func(a *QiitaAPI) {
+		a.userAgent = userAgent
+	}
+}
+
+// WithConcurrency は同時に送信するリクエストの上限を指定する
+// レート制限の残り回数は並行するリクエスト全体で共有される
+func WithConcurrency(n int) Option {
+	return func(a *QiitaAPI) {
+		a.concurrency = max(1, n)
+	}
+}
+
+// 同時に送信するリクエストの上限
+func (a QiitaAPI) Concurrency() int {
+	return a.concurrency
+}
+
+// WithRequestTimeout はリクエスト1件あたりのタイムアウトを指定する
+// レスポンスボディの読み込みまでを含み、レート制限による待機時間は含まない
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(a *QiitaAPI) {
+		a.requestTimeout = max(0, timeout)
+	}
+}
+
+// WithMiddleware はhttp.ClientのTransportをラップするミドルウェアを追加する
+// 先に指定したものほど外側で実行される
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(a *QiitaAPI) {
+		a.middlewares = append(a.middlewares, middlewares...)
+	}
+}
+
+// 指定されたオプションを元に、リクエストに利用するhttp.Clientを組み立てる
+// 呼び出し元のhttp.Clientを書き換えないよう、ミドルウェアがある場合はコピーを作る
+func (a *QiitaAPI) buildClient() {
+	if len(a.middlewares) == 0 {
+		return
+	}
+
+	transport := a.client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(a.middlewares) - 1; i >= 0; i-- {
+		transport = a.middlewares[i](transport)
+	}
+
+	client := *a.client
+	client.Transport = transport
+	a.client = &client
+}
+
+func (a *QiitaAPI) buildBaseURL(domain string) {
+	if a.requestBaseApiUrl != "" {
+		return
+	}
+	a.requestBaseApiUrl = fmt.Sprintf("%s://%s/api/v2", a.scheme, domain)
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// QiitaAPIのper_pageの最大値
+	maxPerPage = 100
+)
+
+var linkRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?([^";]+)"?`)
+
+// Page はページングされたAPIのレスポンス
+type Page[T any] struct {
+	Items   []T
+	Number  int    // ページ番号
+	Total   int    // Total-Countヘッダーの値, ヘッダーがない場合は-1
+	NextURL string // Linkヘッダーのrel="next", 最終ページの場合は空
+}
+
+// Pager はページングされたAPIを1ページずつ取得するイテレータ
+// Linkヘッダーのrel="next"を辿り、Linkヘッダーがない場合はpageを進めて取得件数がTotal-Countに達するまで取得する
+type Pager[T any] struct {
+	api          QiitaAPI
+	u            *url.URL
+	perPage      int
+	page         int    // 次に取得するページ番号
+	next         string // 次に取得するURL, 最後のページを取得した後は空
+	requireTotal bool   // Total-Countヘッダーがない場合はエラーにする
+}
+
+// requestUrlのpage, per_pageを指定したページから取得するPagerを生成する
+func newPager[T any](a QiitaAPI, requestUrl string, page, perPage int) (*Pager[T], error) {
+	u, err := url.Parse(requestUrl)
+	if err != nil {
+		return nil, a.wrapError(err)
+	}
+	p := &Pager[T]{api: a, u: u, perPage: perPage}
+	p.setPage(page)
+	return p, nil
+}
+
+// pageのURLを次に取得する
+func (p *Pager[T]) setPage(page int) {
+	q := p.u.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("per_page", strconv.Itoa(p.perPage))
+	p.u.RawQuery = q.Encode()
+	p.page, p.next = page, p.u.String()
+}
+
+// 最後のページまで取得した場合はtrue
+func (p *Pager[T]) Done() bool {
+	return p.next == ""
+}
+
+// 次に取得するページ番号
+func (p *Pager[T]) Page() int {
+	return p.page
+}
+
+// 次のページを取得する
+func (p *Pager[T]) Next(ctx context.Context) (Page[T], error) {
+	if p.Done() {
+		return Page[T]{}, fmt.Errorf("no more pages")
+	}
+	page, err := requestPage[T](ctx, p.api, p.next)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if p.requireTotal && page.Total < 0 {
+		return Page[T]{}, p.api.wrapError(fmt.Errorf("Total-Count header is missing, url: %s", p.next))
+	}
+	page.Number = p.page
+
+	switch {
+	case len(page.Items) == 0:
+		p.next = ""
+	case page.NextURL != "":
+		p.page, p.next = p.page+1, page.NextURL
+	case page.Total > p.page*p.perPage:
+		p.setPage(p.page + 1)
+	default:
+		p.next = ""
+	}
+	return page, nil
+}
+
+// 1ページ分のリクエストを送信し、レスポンスをPageに格納する
+func requestPage[T any](ctx context.Context, a QiitaAPI, requestUrl string) (Page[T], error) {
+	res, err := a.get(ctx, requestUrl)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	defer res.Body.Close()
+
+	page := Page[T]{
+		Total:   -1,
+		NextURL: parseLinkHeader(res.Header.Get("Link"))["next"],
+	}
+	if v := res.Header.Get("Total-Count"); v != "" {
+		total, err := strconv.Atoi(v)
+		if err != nil {
+			return Page[T]{}, a.wrapError(fmt.Errorf("invalid Total-Count header: %w", err))
+		}
+		page.Total = total
+	}
+
+	page.Items = make([]T, 0)
+	if err := json.NewDecoder(res.Body).Decode(&page.Items); err != nil {
+		return Page[T]{}, a.wrapError(fmt.Errorf("failed to decode response, url: %s: %w", requestUrl, err))
+	}
+
+	return page, nil
+}
+
+// 全てのページを取得して結合する
+func requestAll[T any](ctx context.Context, a QiitaAPI, requestUrl string) ([]T, error) {
+	pager, err := newPager[T](a, requestUrl, 1, maxPerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0)
+	for !pager.Done() {
+		page, err := pager.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
+
+// Linkヘッダーをrelをキーとしたマップに変換する
+// 例: <https://qiita.com/api/v2/items?page=2>; rel="next", <...>; rel="last"
+func parseLinkHeader(header string) map[string]string {
+	links := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		m := linkRegexp.FindStringSubmatch(part)
+		if len(m) < 3 {
+			continue
+		}
+		for _, rel := range strings.Fields(m[2]) {
+			links[rel] = m[1]
+		}
+	}
+	return links
+}
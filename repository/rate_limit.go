@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 残りリクエスト数がこの値以下になったら、リセットまで待機する
+	defaultRateLimitReserve = 5
+	// リセット時刻の誤差を考慮して追加で待機する時間
+	rateLimitResetMargin = time.Second
+	// QiitaAPIの利用制限の期間 (1時間あたりのリクエスト数)
+	rateLimitWindow = time.Hour
+)
+
+// RateLimit はレスポンスヘッダー (Rate-Limit, Rate-Remaining, Rate-Reset) から得られるレート制限の状態
+// https://qiita.com/api/v2/docs#%E5%88%A9%E7%94%A8%E5%88%B6%E9%99%90
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// リクエスト前に待機し、APIの利用制限を超えないようにする
+type rateLimiter struct {
+	mu          sync.Mutex
+	state       RateLimit
+	known       bool // 一度でもレート制限のヘッダーを受け取ったか
+	reserve     int
+	minInterval time.Duration
+	last        time.Time
+	logger      *slog.Logger
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		reserve: defaultRateLimitReserve,
+		logger:  slog.Default(),
+	}
+}
+
+// WithRateLimitReserve は残りリクエスト数がreserve以下になった時点でリセットまで待機するようにする
+func WithRateLimitReserve(reserve int) Option {
+	return func(a *QiitaAPI) {
+		a.limiter.reserve = max(0, reserve)
+	}
+}
+
+// WithMinInterval はリクエスト間の最小の間隔を指定する
+// 並行してリクエストする場合も、リクエストの開始はこの間隔を空ける
+func WithMinInterval(interval time.Duration) Option {
+	return func(a *QiitaAPI) {
+		a.limiter.minInterval = max(0, interval)
+	}
+}
+
+// 最後に受け取ったレート制限の状態を取得する
+// まだヘッダーを受け取っていない場合、okはfalseになる
+func (a QiitaAPI) RateLimit() (rl RateLimit, ok bool) {
+	a.limiter.mu.Lock()
+	defer a.limiter.mu.Unlock()
+	return a.limiter.state, a.limiter.known
+}
+
+// EstimateDuration はAPIへのapiRequests件のリクエストとassetRequests件のアセットのダウンロードにかかる時間を見積もる
+// 1件あたりの所要時間latencyと並行数, 最小間隔, 最後に受け取ったレート制限の状態から計算する
+// アセットはレート制限の対象外のため、リセットまでの待機はAPIへのリクエストのみで計算する
+// レート制限の状態が分からない場合は、リセットまでの待機時間を含めない
+func (a QiitaAPI) EstimateDuration(apiRequests, assetRequests int, latency time.Duration) time.Duration {
+	l := a.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 最小間隔はアセットを含む全てのリクエストの間で空ける
+	requests := apiRequests + assetRequests
+	d := time.Duration(requests) * latency / time.Duration(max(1, a.concurrency))
+	d = max(d, time.Duration(requests)*l.minInterval)
+
+	if l.known && l.state.Limit > l.reserve {
+		available := max(0, l.state.Remaining-l.reserve)
+		if rest := apiRequests - available; rest > 0 {
+			// 最初のリセットまで待機し、その後は期間ごとに上限まで送信する
+			perWindow := l.state.Limit - l.reserve
+			windows := (rest + perWindow - 1) / perWindow
+			d += max(0, time.Until(l.state.Reset)) + time.Duration(windows-1)*rateLimitWindow
+		}
+	}
+	return d
+}
+
+// リクエストを送信してよい状態になるまで待機する
+// APIへのリクエスト (api=true) は残りリクエスト数が少ない場合にリセット時刻まで待機し、残り回数を減らす
+// 最小間隔は全てのリクエストの間で空ける
+// 待機中はロックを解放し、他のリクエストのレスポンスによる状態の更新や RateLimit() を妨げない
+// ctxがキャンセルされた場合は待機を中断してエラーを返す
+func (l *rateLimiter) wait(ctx context.Context, api bool) error {
+	for {
+		l.mu.Lock()
+		var d time.Duration
+		limited := api && l.known && l.state.Remaining <= l.reserve
+		if limited {
+			if d = time.Until(l.state.Reset) + rateLimitResetMargin; d <= 0 {
+				// リセット後は上限まで回復している想定
+				l.state.Remaining = l.state.Limit
+				limited = false
+			}
+		}
+		if !limited {
+			d = l.minInterval - time.Since(l.last)
+		}
+		if d <= 0 {
+			l.last = time.Now()
+			// 並行してリクエストする場合に上限を超えないよう、レスポンスを待たずに残り回数を減らしておく
+			if api && l.known && l.state.Remaining > 0 {
+				l.state.Remaining--
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		state := l.state
+		l.mu.Unlock()
+
+		if limited {
+			l.logger.WarnContext(ctx, "レート制限のため待機します", "wait", d.Round(time.Second),
+				"remaining", state.Remaining, "reset", state.Reset.Format(time.DateTime))
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// 指定した時間待機する, ctxがキャンセルされた場合は中断する
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// レスポンスヘッダーからレート制限の状態を更新する
+// ヘッダーがない場合 (S3のアセットなど) は何もしない
+func (l *rateLimiter) update(h http.Header) {
+	limit, err := strconv.Atoi(h.Get("Rate-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("Rate-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(h.Get("Rate-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+	l.known = true
+}
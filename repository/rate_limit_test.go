@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+// レート制限の状態を受け取った後のlimiter
+func newTestLimiter(limit, remaining int, reset time.Time) *rateLimiter {
+	l := newRateLimiter()
+	l.minInterval = 0
+	l.state = RateLimit{Limit: limit, Remaining: remaining, Reset: reset}
+	l.known = true
+	return l
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name          string
+		remaining     int
+		reset         time.Duration // 現在時刻からのリセットまでの時間
+		api           bool
+		wantBlock     bool
+		wantRemaining int
+	}{
+		{"残りがある", 100, time.Hour, true, false, 99},
+		{"残りがreserve以下", defaultRateLimitReserve, time.Hour, true, true, defaultRateLimitReserve},
+		{"アセットは待機しない", 0, time.Hour, false, false, 0},
+		{"リセット後は回復する", 0, -time.Minute, true, false, 999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(1000, tt.remaining, time.Now().Add(tt.reset))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := l.wait(ctx, tt.api)
+			if blocked := errors.Is(err, context.DeadlineExceeded); blocked != tt.wantBlock {
+				t.Fatalf("wait() = %v, want blocked=%v", err, tt.wantBlock)
+			}
+			if l.state.Remaining != tt.wantRemaining {
+				t.Errorf("remaining = %d, want %d", l.state.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMinInterval(t *testing.T) {
+	l := newRateLimiter()
+	l.minInterval = 20 * time.Millisecond
+
+	start := time.Now()
+	for range 4 {
+		// 最小間隔はアセットのダウンロードにも適用する
+		if err := l.wait(context.Background(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 3*l.minInterval {
+		t.Errorf("elapsed = %s, want >= %s", elapsed, 3*l.minInterval)
+	}
+}
+
+// 待機中もロックを解放し、RateLimit() や他のレスポンスによる状態の更新を妨げない
+func TestRateLimiterWaitReleasesLock(t *testing.T) {
+	api := &QiitaAPI{limiter: newTestLimiter(1000, 0, time.Now().Add(time.Hour))}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- api.limiter.wait(ctx, true) }()
+
+	time.Sleep(20 * time.Millisecond)
+	got := make(chan RateLimit)
+	go func() {
+		rl, _ := api.RateLimit()
+		got <- rl
+	}()
+	select {
+	case rl := <-got:
+		if rl.Remaining != 0 {
+			t.Errorf("remaining = %d, want 0", rl.Remaining)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RateLimit() is blocked while waiting")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() = %v, want context.Canceled", err)
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	l := newRateLimiter()
+	h := http.Header{}
+	h.Set("Rate-Limit", "1000")
+	h.Set("Rate-Remaining", "998")
+	h.Set("Rate-Reset", strconv.FormatInt(reset.Unix(), 10))
+	l.update(h)
+
+	want := RateLimit{Limit: 1000, Remaining: 998, Reset: reset}
+	if !l.known || l.state.Limit != want.Limit || l.state.Remaining != want.Remaining || !l.state.Reset.Equal(want.Reset) {
+		t.Errorf("state = %+v (known=%v), want %+v", l.state, l.known, want)
+	}
+
+	// ヘッダーがないレスポンス (S3のアセットなど) では更新しない
+	l.update(http.Header{})
+	if l.state.Remaining != want.Remaining {
+		t.Errorf("state is updated without headers: %+v", l.state)
+	}
+}
+
+// フェイクサーバーのレート制限に対して、残りがreserve以下になったらリセットまで待機する
+func TestRateLimitPacing(t *testing.T) {
+	server := qiitafake.NewServer(qiitafake.Fixtures{Articles: testArticles(1)})
+	defer server.Close()
+	reset := time.Now().Add(time.Second)
+	server.SetRateLimit(10, 2, reset)
+	api := newTestAPI(t, server, WithRateLimitReserve(1))
+
+	for i := range 3 {
+		if _, _, err := api.RequestArticles("page=1&per_page=20"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	// 2件目の後は残りが1件 (reserve) のため、3件目はリセットまで待機する
+	if time.Now().Before(reset) {
+		t.Errorf("3rd request was sent before the reset")
+	}
+	if n := len(server.Requests()); n != 3 {
+		t.Errorf("requests = %d, want 3 (no rate limit errors)", n)
+	}
+	if rl, ok := api.RateLimit(); !ok || rl.Limit != 10 {
+		t.Errorf("RateLimit() = %+v, %v", rl, ok)
+	}
+}
+func TestEstimateDuration(t *testing.T) {
+	reset := 30 * time.Minute
+	tests := []struct {
+		name      string
+		known     bool
+		remaining int
+		api       int
+		assets    int
+		want      time.Duration
+	}{
+		{"制限の状態が不明", false, 0, 10, 10, 20 * time.Second},
+		{"残りに収まる", true, 100, 50, 0, 50 * time.Second},
+		{"アセットは制限の対象外", true, 100, 50, 1000, 1050 * time.Second},
+		{"リセットまで待機する", true, 15, 20, 0, 20*time.Second + reset},
+		{"複数の期間", true, 5, 1000, 0, 1000*time.Second + reset + rateLimitWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &QiitaAPI{limiter: newTestLimiter(1000, tt.remaining, time.Now().Add(reset)), concurrency: 1}
+			api.limiter.known = tt.known
+
+			got := api.EstimateDuration(tt.api, tt.assets, time.Second)
+			// リセットまでの時間は実行中に僅かに減る
+			if diff := tt.want - got; diff < 0 || diff > time.Second {
+				t.Errorf("EstimateDuration(%d, %d) = %s, want %s", tt.api, tt.assets, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy はリクエストが失敗した場合のリトライの設定
+type RetryPolicy struct {
+	MaxAttempts       int           // 最初の1回を含む試行回数, 1以下の場合はリトライしない
+	BaseDelay         time.Duration // 1回目のリトライまでの待機時間, 以降は2倍ずつ増える
+	MaxDelay          time.Duration // 待機時間の上限
+	Jitter            float64       // 待機時間に加えるランダムな揺らぎの割合 (0〜1)
+	RetryableStatuses []int         // リトライするステータスコード
+}
+
+// デフォルトのリトライの設定
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Jitter:      0.2,
+	RetryableStatuses: []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// WithRetryPolicy はリクエストが失敗した場合のリトライの設定を指定する
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *QiitaAPI) {
+		a.retryPolicy = policy
+	}
+}
+
+// ステータスコードがリトライの対象かどうか
+func (p RetryPolicy) retryableStatus(res *http.Response) bool {
+	return slices.Contains(p.RetryableStatuses, res.StatusCode)
+}
+
+// attempt回目 (1始まり) の失敗の後に待機する時間
+// Retry-Afterヘッダーが指定されている場合はそちらを優先する
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (rand.Float64()*2 - 1))
+	}
+	return max(0, d)
+}
+
+// リトライしながらGETリクエストを送信する
+// ネットワークエラーとリトライ対象のステータスコードの場合はリトライし、それ以外のレスポンスはそのまま返す
+// 全ての試行が失敗した場合は、各試行のエラーを結合して返す
+// newRequestは試行ごとにリクエストを作成する (APIとアセットで付与するヘッダーが異なる)
+func (a QiitaAPI) doWithRetry(ctx context.Context, requestUrl string, newRequest func(context.Context, string) (*http.Request, error)) (*http.Response, error) {
+	maxAttempts := max(1, a.retryPolicy.MaxAttempts)
+
+	var errs []error
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx, requestUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		res, err := a.do(req)
+		switch {
+		case err != nil:
+			// 中断された場合はリトライしない
+			if ctx.Err() != nil {
+				return nil, errors.Join(append(errs, a.wrapError(err))...)
+			}
+			err = a.wrapError(err)
+		case a.retryPolicy.retryableStatus(res) || isRateLimitedResponse(res):
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+			apiErr := newAPIError(res)
+			res.Body.Close()
+			err = apiErr
+		default:
+			return res, nil
+		}
+
+		errs = append(errs, fmt.Errorf("attempt %d/%d: %w", attempt, maxAttempts, err))
+		if attempt >= maxAttempts {
+			return nil, errors.Join(errs...)
+		}
+
+		d := a.retryPolicy.delay(attempt, retryAfter)
+		a.logger.WarnContext(ctx, "リトライします", "attempt", attempt, "max_attempts", maxAttempts, "delay", d.Round(time.Millisecond), "error", err)
+		if err := sleep(ctx, d); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+	}
+}
+
+// レート制限を超えたことによる403かどうか
+// レート制限の待機はrateLimiterが行うため、ここではリトライの対象かどうかのみ判定する
+func isRateLimitedResponse(res *http.Response) bool {
+	return res.StatusCode == http.StatusForbidden && res.Header.Get("Rate-Remaining") == "0"
+}
+
+// Retry-Afterヘッダーを待機時間に変換する
+// 秒数とHTTP-dateの両方の形式に対応する
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t))
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		fault        qiitafake.Fault
+		wantErr      bool
+		wantStatus   int // wantErrの場合のAPIErrorのステータスコード
+		wantRequests int
+		minElapsed   time.Duration
+	}{
+		{
+			name:         "500の後に成功",
+			fault:        qiitafake.Fault{Path: "/api/v2/items", Status: http.StatusInternalServerError, Times: 2},
+			wantRequests: 3,
+		},
+		{
+			name:         "Retry-Afterに従って待機する",
+			fault:        qiitafake.Fault{Path: "/api/v2/items", Status: http.StatusTooManyRequests, Times: 1, RetryAfter: time.Second},
+			wantRequests: 2,
+			minElapsed:   time.Second,
+		},
+		{
+			name:         "試行回数の上限",
+			fault:        qiitafake.Fault{Path: "/api/v2/items", Status: http.StatusServiceUnavailable},
+			wantErr:      true,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: testRetryPolicy.MaxAttempts,
+		},
+		{
+			name:         "404はリトライしない",
+			fault:        qiitafake.Fault{Path: "/api/v2/items", Status: http.StatusNotFound},
+			wantErr:      true,
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := qiitafake.NewServer(qiitafake.Fixtures{Articles: testArticles(3)})
+			defer server.Close()
+			server.InjectFault(tt.fault)
+
+			start := time.Now()
+			articles, _, err := newTestAPI(t, server).RequestArticles("page=1&per_page=20")
+			elapsed := time.Since(start)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("err = nil, want error")
+				}
+				if StatusCode(err) != tt.wantStatus {
+					t.Errorf("err = %v, want status %d", err, tt.wantStatus)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if len(articles) != 3 {
+				t.Errorf("len = %d, want 3", len(articles))
+			}
+			if n := len(server.Requests()); n != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", n, tt.wantRequests)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("elapsed = %s, want >= %s", elapsed, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"invalid", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	// HTTP-dateは現在時刻までの時間
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 58*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, want about 1h", future, got)
+	}
+}
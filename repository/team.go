@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/qiita_export/internal/workerpool"
+	"github.com/qiita_export/models"
+)
+
+// 1件のリソースを取得する (ページングしないエンドポイント)
+func requestOne[T any](ctx context.Context, a QiitaAPI, requestUrl string) (*T, error) {
+	res, err := a.get(ctx, requestUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var v T
+	if err := json.NewDecoder(res.Body).Decode(&v); err != nil {
+		return nil, a.wrapError(fmt.Errorf("failed to decode response, url: %s: %w", requestUrl, err))
+	}
+	return &v, nil
+}
+
+// APIのベースURLにパスを結合する
+func (a QiitaAPI) endpointURL(elem ...string) (string, error) {
+	requestUrl, err := url.JoinPath(a.requestBaseApiUrl, elem...)
+	if err != nil {
+		return "", a.wrapError(err)
+	}
+	return requestUrl, nil
+}
+
+// アクセストークンに紐づくユーザーを取得する
+// GET /api/v2/authenticated_user
+// https://qiita.com/api/v2/docs#get-apiv2authenticated_user
+func (a QiitaAPI) RequestAuthenticatedUser() (*models.AuthenticatedUser, error) {
+	return a.RequestAuthenticatedUserContext(context.Background())
+}
+
+// RequestAuthenticatedUserのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestAuthenticatedUserContext(ctx context.Context) (*models.AuthenticatedUser, error) {
+	requestUrl, err := a.endpointURL("authenticated_user")
+	if err != nil {
+		return nil, err
+	}
+	return requestOne[models.AuthenticatedUser](ctx, a, requestUrl)
+}
+
+// チームのメンバーを取得する
+// GET /api/v2/team_memberships にリクエストを送信し、全ページ分を格納する
+func (a QiitaAPI) RequestTeamMemberships() ([]models.TeamMembership, error) {
+	return a.RequestTeamMembershipsContext(context.Background())
+}
+
+// RequestTeamMembershipsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestTeamMembershipsContext(ctx context.Context) ([]models.TeamMembership, error) {
+	requestUrl, err := a.endpointURL("team_memberships")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.TeamMembership](ctx, a, requestUrl)
+}
+
+// 招待中のメンバーを取得する (チームの管理者のみ)
+// GET /api/v2/team_invitations
+// https://qiita.com/api/v2/docs#get-apiv2team_invitations
+func (a QiitaAPI) RequestTeamInvitations() ([]models.TeamInvitation, error) {
+	return a.RequestTeamInvitationsContext(context.Background())
+}
+
+// RequestTeamInvitationsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestTeamInvitationsContext(ctx context.Context) ([]models.TeamInvitation, error) {
+	requestUrl, err := a.endpointURL("team_invitations")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.TeamInvitation](ctx, a, requestUrl)
+}
+
+// グループを取得する
+// GET /api/v2/groups にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2groups
+func (a QiitaAPI) RequestGroups() ([]models.Group, error) {
+	return a.RequestGroupsContext(context.Background())
+}
+
+// RequestGroupsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestGroupsContext(ctx context.Context) ([]models.Group, error) {
+	requestUrl, err := a.endpointURL("groups")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.Group](ctx, a, requestUrl)
+}
+
+// グループのメンバーを取得する
+// GET /api/v2/groups/:url_name/members にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2groupsurl_namemembers
+func (a QiitaAPI) RequestGroupMembers(urlName string) ([]models.GroupMember, error) {
+	return a.RequestGroupMembersContext(context.Background(), urlName)
+}
+
+// RequestGroupMembersのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestGroupMembersContext(ctx context.Context, urlName string) ([]models.GroupMember, error) {
+	requestUrl, err := a.endpointURL("groups", urlName, "members")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.GroupMember](ctx, a, requestUrl)
+}
+
+// タグを取得する
+// GET /api/v2/tags にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2tags
+func (a QiitaAPI) RequestTags() ([]models.Tag, error) {
+	return a.RequestTagsContext(context.Background())
+}
+
+// RequestTagsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestTagsContext(ctx context.Context) ([]models.Tag, error) {
+	requestUrl, err := a.endpointURL("tags")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.Tag](ctx, a, requestUrl)
+}
+
+// テンプレートを取得する
+// GET /api/v2/templates にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2templates
+func (a QiitaAPI) RequestTemplates() ([]models.Template, error) {
+	return a.RequestTemplatesContext(context.Background())
+}
+
+// RequestTemplatesのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestTemplatesContext(ctx context.Context) ([]models.Template, error) {
+	requestUrl, err := a.endpointURL("templates")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.Template](ctx, a, requestUrl)
+}
+
+// プロジェクトとそのコメントを取得する
+// GET /api/v2/projects にリクエストを送信し、全ページ分を格納する
+// https://qiita.com/api/v2/docs#get-apiv2projects
+func (a QiitaAPI) RequestProjects() ([]models.Project, error) {
+	return a.RequestProjectsContext(context.Background())
+}
+
+// RequestProjectsのcontext.Contextを受け取る版
+func (a QiitaAPI) RequestProjectsContext(ctx context.Context) ([]models.Project, error) {
+	requestUrl, err := a.endpointURL("projects")
+	if err != nil {
+		return nil, err
+	}
+	projects, err := requestAll[models.Project](ctx, a, requestUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// プロジェクトのコメントを並行して取得する
+	err = workerpool.Run(len(projects), a.concurrency, func(i int) error {
+		comments, err := a.requestProjectComments(ctx, projects[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to get project comments: %w", err)
+		}
+		projects[i].Comments = comments
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// プロジェクトのコメントを取得する
+// GET /api/v2/projects/:project_id/comments にリクエストを送信し、全ページ分を格納する
+func (a QiitaAPI) requestProjectComments(ctx context.Context, projectID int) ([]models.Comment, error) {
+	requestUrl, err := a.endpointURL("projects", strconv.Itoa(projectID), "comments")
+	if err != nil {
+		return nil, err
+	}
+	return requestAll[models.Comment](ctx, a, requestUrl)
+}
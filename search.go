@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qiita_export/internal/search"
+)
+
+// 検索のインデックスのデフォルトの保存先
+// 静的HTMLのサイトの search/ に置くと、サイトの記事のページにリンクする
+const defaultIndexDir = "archive/search"
+
+// indexコマンド
+// 出力ディレクトリの記事から全文検索のインデックスを作成する
+func runIndex(args []string) error {
+	fs := newFlagSet("index")
+	exportDir := addDirFlag(fs)
+	indexDir := fs.String("index", defaultIndexDir, "インデックスの出力先")
+	shards := fs.Int("shards", search.DefaultShards, "シャード数")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	return search.Build(search.Options{ExportDir: *exportDir, IndexDir: *indexDir, Shards: *shards})
+}
+
+// searchコマンド
+// indexコマンドで作成したインデックスを検索する
+func runSearch(args []string) error {
+	fs := newFlagSet("search")
+	indexDir := fs.String("index", defaultIndexDir, "インデックスのディレクトリ")
+	limit := fs.Int("limit", 20, "表示する件数, 0の場合は全件")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		return errors.New("検索語を指定してください")
+	}
+	idx, err := search.Open(*indexDir)
+	if err != nil {
+		return err
+	}
+	results, err := idx.Search(query, *limit)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Printf("%4d  %s  %s  [%s] %s\n", r.Score, r.Created, r.Title, r.Group, r.URL)
+	}
+	fmt.Printf("%d 件\n", len(results))
+	return nil
+}
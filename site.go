@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// 静的サイトジェネレーター向けの出力形式
+const (
+	siteFormatHugo   = "hugo"   // <site>/content/posts/<id>/index.md (ページバンドル)
+	siteFormatJekyll = "jekyll" // <site>/_posts/YYYY-MM-DD-<id>.md, アセットは <site>/assets/<id>/
+)
+
+// 出力ディレクトリに保存する場合のディレクトリ名
+// グループのURL名として使えない _ から始め、グループのディレクトリと重ならないようにする
+const siteDirName = "_site"
+
+// フロントマターの形式
+const (
+	frontMatterYAML = "yaml"
+	frontMatterTOML = "toml"
+)
+
+// 記事をフロントマター付きのMarkdownとして静的サイトのレイアウトで保存する
+// 記事のディレクトリ (<group>/<id>) への保存に加えて出力する
+type siteLayout struct {
+	format      string
+	frontMatter string
+	dir         string
+}
+
+// formatが空の場合はnilを返す
+func newSiteLayout(format, frontMatter, dir string) (*siteLayout, error) {
+	if format == "" {
+		return nil, nil
+	}
+	if format != siteFormatHugo && format != siteFormatJekyll {
+		return nil, fmt.Errorf("format must be %q or %q", siteFormatHugo, siteFormatJekyll)
+	}
+	if frontMatter != frontMatterYAML && frontMatter != frontMatterTOML {
+		return nil, fmt.Errorf("front_matter must be %q or %q", frontMatterYAML, frontMatterTOML)
+	}
+	if format == siteFormatJekyll && frontMatter != frontMatterYAML {
+		return nil, fmt.Errorf("jekyll supports only %q front matter", frontMatterYAML)
+	}
+	return &siteLayout{format: format, frontMatter: frontMatter, dir: dir}, nil
+}
+
+// 記事のMarkdownのパス, アセットを配置するディレクトリ, Markdownから参照するアセットのパスのプレフィックス
+// スラッグにはタイトルが変わっても変化しない記事IDを使う
+func (s *siteLayout) paths(v *models.Article) (mdPath, assetDir, assetPrefix string) {
+	switch s.format {
+	case siteFormatJekyll:
+		name := fmt.Sprintf("%s-%s.md", v.CreatedAt.Format(time.DateOnly), v.ID)
+		// Jekyllの記事のURLはパーマリンクの設定によって変わるため、サイトのルートからのパスで参照する
+		return filepath.Join(s.dir, "_posts", name), filepath.Join(s.dir, "assets", v.ID), "/assets/" + v.ID + "/"
+	default:
+		bundleDir := filepath.Join(s.dir, "content", "posts", v.ID)
+		return filepath.Join(bundleDir, "index.md"), bundleDir, ""
+	}
+}
+
+// 記事をフロントマター付きで保存し、ダウンロードに成功したアセットを配置する
+// アセットは記事のディレクトリ (またはアセットストア) のファイルへのハードリンクにする
+func (s *siteLayout) write(v *models.Article, results []repository.AssetResult) error {
+	mdPath, assetDir, assetPrefix := s.paths(v)
+	if err := os.MkdirAll(filepath.Dir(mdPath), 0777); err != nil {
+		return err
+	}
+
+	paths := make(map[string]string, len(results))
+	for _, r := range results {
+		if r.Err != nil || r.LocalPath == "" {
+			continue
+		}
+		if err := os.MkdirAll(assetDir, 0777); err != nil {
+			return err
+		}
+		name := filepath.Base(r.LocalPath)
+		if err := linkFile(r.LocalPath, filepath.Join(assetDir, name)); err != nil {
+			return fmt.Errorf("failed to link asset: %w", err)
+		}
+		paths[r.URL] = assetPrefix + name
+	}
+
+	frontMatter, err := s.renderFrontMatter(v)
+	if err != nil {
+		return err
+	}
+	body := repository.ReplaceAssetURLs(v.Body, paths)
+	if s.format == siteFormatJekyll {
+		body = jekyllRaw(body)
+	}
+	if err := writeFileAtomic(mdPath, []byte(frontMatter+"\n"+body), 0666); err != nil {
+		return fmt.Errorf("failed to write site markdown: %w", err)
+	}
+	return nil
+}
+
+// Jekyllが本文の {{ }} や {% %} をLiquidのタグとして解釈しないよう、raw タグで囲む
+// 本文中の endraw は raw の外でLiquidの文字列として出力する
+func jekyllRaw(body string) string {
+	body = strings.ReplaceAll(body, "{% endraw %}", "{% endraw %}{{ '{% endraw %}' }}{% raw %}")
+	return "{% raw %}\n" + body + "\n{% endraw %}\n"
+}
+
+// 削除された記事のファイルを削除する
+func (s *siteLayout) remove(v *models.Article) error {
+	mdPath, assetDir, _ := s.paths(v)
+	if err := os.Remove(mdPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(assetDir)
+}
+
+// フロントマターを生成する
+// 文字列はJSONの形式でエスケープする (YAML, TOMLのダブルクォートの文字列としても有効)
+func (s *siteLayout) renderFrontMatter(v *models.Article) (string, error) {
+	tags := make([]string, 0, len(v.Tags))
+	for _, t := range v.Tags {
+		tags = append(tags, t.Name)
+	}
+	var author, group string
+	if v.User != nil {
+		author = v.User.ID
+	}
+	if v.Group != nil {
+		group = v.Group.Name
+	}
+	lastmodKey := "lastmod"
+	if s.format == siteFormatJekyll {
+		lastmodKey = "last_modified_at" // jekyll-last-modified-at のキー
+	}
+
+	fields := []struct {
+		key   string
+		value any
+	}{
+		{"title", v.Title},
+		{"date", v.CreatedAt},
+		{lastmodKey, v.UpdatedAt},
+		{"tags", tags},
+		{"author", author},
+		{"group", group},
+		{"original_url", v.URL},
+		{"qiita_id", v.ID},
+	}
+
+	delim, sep := "---", ": "
+	if s.frontMatter == frontMatterTOML {
+		delim, sep = "+++", " = "
+	}
+
+	var b strings.Builder
+	b.WriteString(delim + "\n")
+	for _, f := range fields {
+		value, err := frontMatterValue(f.value)
+		if err != nil {
+			return "", fmt.Errorf("failed to render front matter %s: %w", f.key, err)
+		}
+		b.WriteString(f.key + sep + value + "\n")
+	}
+	b.WriteString(delim + "\n")
+
+	return b.String(), nil
+}
+
+// 日時はRFC 3339の形式でクォートせずに出力する (YAMLのtimestamp, TOMLのOffset Date-Time)
+func frontMatterValue(v any) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339), nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// エスケープが必要な文字を含む記事
+func testSiteArticle() *models.Article {
+	v := testArticle(testID1, "\"Go\": 設計\nの話 # です", "dev", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC))
+	v.Tags = []models.Tagging{{Name: "Go"}, {Name: "a: b"}}
+	v.URL = "https://example.qiita.com/alice/items/" + testID1
+	return &v
+}
+
+// フロントマターの区切りの間を取り出す
+func frontMatterBody(t *testing.T, md, delim string) string {
+	t.Helper()
+	rest, ok := strings.CutPrefix(md, delim+"\n")
+	if !ok {
+		t.Fatalf("front matter does not start with %s: %q", delim, md)
+	}
+	body, _, ok := strings.Cut(rest, delim+"\n")
+	if !ok {
+		t.Fatalf("front matter is not closed: %q", md)
+	}
+	return body
+}
+
+// クォート, コロン, 改行を含む値もYAML, TOMLとして元の値に戻る
+func TestRenderFrontMatter(t *testing.T) {
+	v := testSiteArticle()
+	type fields struct {
+		Title       string    `yaml:"title" toml:"title"`
+		Date        time.Time `yaml:"date" toml:"date"`
+		Tags        []string  `yaml:"tags" toml:"tags"`
+		Author      string    `yaml:"author" toml:"author"`
+		Group       string    `yaml:"group" toml:"group"`
+		OriginalURL string    `yaml:"original_url" toml:"original_url"`
+		QiitaID     string    `yaml:"qiita_id" toml:"qiita_id"`
+	}
+	want := fields{
+		Title:       v.Title,
+		Date:        v.CreatedAt,
+		Tags:        []string{"Go", "a: b"},
+		Author:      "alice",
+		Group:       "dev",
+		OriginalURL: v.URL,
+		QiitaID:     testID1,
+	}
+
+	tests := []struct {
+		format, frontMatter, delim, lastmodKey string
+		unmarshal                              func([]byte, any) error
+	}{
+		{siteFormatHugo, frontMatterYAML, "---", "lastmod", yaml.Unmarshal},
+		{siteFormatHugo, frontMatterTOML, "+++", "lastmod", toml.Unmarshal},
+		{siteFormatJekyll, frontMatterYAML, "---", "last_modified_at", yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.frontMatter, func(t *testing.T) {
+			s, err := newSiteLayout(tt.format, tt.frontMatter, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			md, err := s.renderFrontMatter(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := frontMatterBody(t, md, tt.delim)
+
+			var got fields
+			if err := tt.unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("failed to parse %q: %v", body, err)
+			}
+			if got.Title != want.Title || !got.Date.Equal(want.Date) || !slices.Equal(got.Tags, want.Tags) ||
+				got.Author != want.Author || got.Group != want.Group || got.OriginalURL != want.OriginalURL || got.QiitaID != want.QiitaID {
+				t.Errorf("front matter = %+v, want %+v", got, want)
+			}
+
+			var raw map[string]any
+			if err := tt.unmarshal([]byte(body), &raw); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := raw[tt.lastmodKey]; !ok {
+				t.Errorf("%s is missing: %v", tt.lastmodKey, raw)
+			}
+		})
+	}
+}
+
+func TestNewSiteLayout(t *testing.T) {
+	tests := []struct {
+		format, frontMatter string
+		wantNil, wantErr    bool
+	}{
+		{"", "", true, false},
+		{siteFormatHugo, frontMatterYAML, false, false},
+		{siteFormatHugo, frontMatterTOML, false, false},
+		{siteFormatJekyll, frontMatterYAML, false, false},
+		{siteFormatJekyll, frontMatterTOML, false, true},
+		{"gatsby", frontMatterYAML, false, true},
+		{siteFormatHugo, "json", false, true},
+	}
+	for _, tt := range tests {
+		s, err := newSiteLayout(tt.format, tt.frontMatter, "site")
+		if (err != nil) != tt.wantErr || (err == nil && (s == nil) != tt.wantNil) {
+			t.Errorf("newSiteLayout(%q, %q) = %v, %v", tt.format, tt.frontMatter, s, err)
+		}
+	}
+}
+
+func TestSitePaths(t *testing.T) {
+	v := testSiteArticle()
+	dir := filepath.Join("out", siteDirName)
+	tests := []struct {
+		format                           string
+		wantMD, wantAssetDir, wantPrefix string
+	}{
+		{siteFormatHugo, filepath.Join(dir, "content", "posts", testID1, "index.md"), filepath.Join(dir, "content", "posts", testID1), ""},
+		// 日付は作成日時のタイムゾーン (JST) で決める
+		{siteFormatJekyll, filepath.Join(dir, "_posts", "2024-01-01-"+testID1+".md"), filepath.Join(dir, "assets", testID1), "/assets/" + testID1 + "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s, err := newSiteLayout(tt.format, frontMatterYAML, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			md, assetDir, prefix := s.paths(v)
+			if md != tt.wantMD || assetDir != tt.wantAssetDir || prefix != tt.wantPrefix {
+				t.Errorf("paths() = %q, %q, %q, want %q, %q, %q", md, assetDir, prefix, tt.wantMD, tt.wantAssetDir, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+// Jekyllの本文はLiquidとして解釈されないよう raw で囲み、アセットはサイトのルートからのパスで参照する
+func TestSiteWriteJekyll(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSiteLayout(siteFormatJekyll, frontMatterYAML, filepath.Join(dir, siteDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetPath := filepath.Join(dir, "a.png")
+	if err := os.WriteFile(assetPath, testPNG, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	v := testSiteArticle()
+	assetURL := "https://example.qiita.com/files/a.png"
+	v.Body = "{{ page.title }} {% endraw %}\n![a](" + assetURL + ")"
+	if err := s.write(v, []repository.AssetResult{{URL: assetURL, LocalPath: assetPath}}); err != nil {
+		t.Fatal(err)
+	}
+
+	mdPath, assetDir, _ := s.paths(v)
+	b, err := os.ReadFile(mdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body, _ := strings.Cut(strings.TrimPrefix(string(b), "---\n"), "---\n")
+	want := "\n{% raw %}\n{{ page.title }} {% endraw %}{{ '{% endraw %}' }}{% raw %}\n![a](/assets/" + testID1 + "/a.png)\n{% endraw %}\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if _, err := os.Stat(filepath.Join(assetDir, "a.png")); err != nil {
+		t.Error(err)
+	}
+}
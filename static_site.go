@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/qiita_export/internal/htmlsite"
+)
+
+// siteコマンド
+// 出力ディレクトリから、サーバーなしで閲覧できる静的なHTMLのサイトを生成する
+func runSite(args []string) error {
+	fs := newFlagSet("site")
+	exportDir := addDirFlag(fs)
+	outputDir := fs.String("out", "archive", "サイトの出力先")
+	assetStoreDir := fs.String("asset_store", "", "エクスポート時に -asset_store を指定した場合のアセットストア")
+	title := fs.String("title", "Qiita Team Archive", "サイトのタイトル")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	return htmlsite.Generate(htmlsite.Options{
+		ExportDir:     *exportDir,
+		OutputDir:     *outputDir,
+		AssetStoreDir: *assetStoreDir,
+		Title:         *title,
+	})
+}
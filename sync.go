@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// 出力ディレクトリに保存済みの記事
+type localArticle struct {
+	dir          string // <outputDir>/<group>/<id>
+	metadataPath string
+	article      *models.Article
+}
+
+// 同期結果
+// 記事を並行して処理するため、muで保護する
+type syncReport struct {
+	mu        sync.Mutex
+	added     []string
+	updated   []string
+	unchanged []string
+	deleted   []string
+	seen      map[string]bool
+}
+
+func newSyncReport() *syncReport {
+	return &syncReport{seen: make(map[string]bool)}
+}
+
+// 出力ディレクトリの <group>/<id>/*_metadata.json を読み込み、記事IDをキーとしたマップにする
+func loadLocalArticles(outputDir string) (map[string]localArticle, error) {
+	exported, err := repository.LoadExportedArticles(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	locals := make(map[string]localArticle, len(exported))
+	for _, e := range exported {
+		locals[e.Article.ID] = localArticle{
+			dir:          e.Dir,
+			metadataPath: e.MetadataPath,
+			article:      e.Article,
+		}
+	}
+
+	return locals, nil
+}
+
+// 記事がローカルのコピーから変更されているかどうかを判定し、結果を記録する
+// 更新日時に加えて、更新日時が変わらないコメント数, 絵文字リアクション数の変化も変更として扱う
+func (r *syncReport) needsExport(v *models.Article, locals map[string]localArticle) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seen[v.ID] = true
+
+	local, ok := locals[v.ID]
+	if !ok {
+		r.added = append(r.added, v.ID)
+		return true
+	}
+
+	saved := local.article
+	if v.UpdatedAt.After(saved.UpdatedAt) || v.CommentsCount != saved.CommentsCount || v.ReactionsCount != saved.ReactionsCount {
+		r.updated = append(r.updated, v.ID)
+		return true
+	}
+
+	r.unchanged = append(r.unchanged, v.ID)
+	return false
+}
+
+// 更新した記事のタイトルやグループが変わっていた場合、古いファイルを削除する
+func removeStaleFiles(local localArticle, artDir, sanitizedTitle string) error {
+	if local.dir != artDir {
+		return os.RemoveAll(local.dir)
+	}
+
+	oldBase := strings.TrimSuffix(filepath.Base(local.metadataPath), "_metadata.json")
+	if oldBase == sanitizedTitle {
+		return nil
+	}
+	for _, path := range []string{local.metadataPath, filepath.Join(local.dir, oldBase+".md")} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// APIから取得できなかった記事を削除済みとして記録し、pruneがtrueの場合はローカルからも削除する
+// siteを指定した場合は、静的サイト用のファイルも削除する
+func (r *syncReport) detectDeleted(locals map[string]localArticle, prune bool, site *siteLayout) error {
+	for id, local := range locals {
+		if r.seen[id] {
+			continue
+		}
+		r.deleted = append(r.deleted, id)
+
+		if prune {
+			if err := os.RemoveAll(local.dir); err != nil {
+				return err
+			}
+			if site != nil {
+				if err := site.remove(local.article); err != nil {
+					return err
+				}
+			}
+			// 空になったグループのディレクトリも削除する
+			groupDir := filepath.Dir(local.dir)
+			if entries, err := os.ReadDir(groupDir); err == nil && len(entries) == 0 {
+				if err := os.Remove(groupDir); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+	}
+	slices.Sort(r.deleted)
+
+	return nil
+}
+
+func (r *syncReport) print() {
+	// 並行して処理した順によらず、同じ結果を表示する
+	slices.Sort(r.added)
+	slices.Sort(r.updated)
+
+	slog.Info("同期結果", "added", len(r.added), "updated", len(r.updated), "unchanged", len(r.unchanged), "deleted", len(r.deleted))
+	for _, id := range r.added {
+		slog.Info("追加された記事", "id", id)
+	}
+	for _, id := range r.updated {
+		slog.Info("更新された記事", "id", id)
+	}
+	for _, id := range r.deleted {
+		slog.Info("削除された記事", "id", id)
+	}
+}
+
+// レポート用の件数
+func (r *syncReport) counts() *reportSync {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &reportSync{Added: len(r.added), Updated: len(r.updated), Unchanged: len(r.unchanged), Deleted: len(r.deleted)}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/qiitafake"
+)
+
+// エクスポート済みの記事に対して、更新, 削除, 追加された記事のみ反映する
+func TestExecuteSync(t *testing.T) {
+	updated := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	before := []models.Article{
+		testArticle(testID1, "更新される記事", "dev", updated),
+		testArticle(testID2, "削除される記事", "dev", updated),
+		testArticle(testID3, "変更のない記事", "", updated),
+	}
+	server := qiitafake.NewServer(qiitafake.Fixtures{Articles: before})
+	defer server.Close()
+	dir := t.TempDir()
+	if err := execute(context.Background(), newTestAPI(t, server), testOptions(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := testArticle(testID1, "タイトルを変更した記事", "dev", updated.Add(time.Hour))
+	after := []models.Article{
+		renamed,
+		before[2],
+		testArticle("0000000000000000000d", "追加された記事", "ops", updated),
+	}
+	server2 := qiitafake.NewServer(qiitafake.Fixtures{Articles: after})
+	defer server2.Close()
+
+	tests := []struct {
+		name        string
+		prune       bool
+		wantRemoved bool
+	}{
+		{"pruneなし", false, false},
+		{"prune", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			work := t.TempDir()
+			if err := os.CopyFS(work, os.DirFS(dir)); err != nil {
+				t.Fatal(err)
+			}
+			sent := len(server2.Requests())
+			opts := testOptions(work)
+			opts.sync, opts.prune = true, tt.prune
+			if err := execute(context.Background(), newTestAPI(t, server2), opts); err != nil {
+				t.Fatal(err)
+			}
+
+			// 変更のない記事はコメントを取得し直さない
+			var exported []string
+			for _, r := range server2.Requests()[sent:] {
+				if u, _ := url.Parse(r); strings.HasSuffix(u.Path, "/comments") {
+					exported = append(exported, strings.Split(u.Path, "/")[4])
+				}
+			}
+			slices.Sort(exported)
+			if want := []string{testID1, "0000000000000000000d"}; !slices.Equal(exported, want) {
+				t.Errorf("exported = %v, want %v", exported, want)
+			}
+			got := opts.stats.sync.counts()
+			if want := (reportSync{Added: 1, Updated: 1, Unchanged: 1, Deleted: 1}); *got != want {
+				t.Errorf("counts = %+v, want %+v", *got, want)
+			}
+
+			// タイトルを変更した記事は古いMarkdownを削除する
+			readMarkdown(t, work, renamed)
+			if _, err := os.Stat(filepath.Join(articleDir(work, &renamed), sanitizeFilename(before[0].Title)+".md")); !os.IsNotExist(err) {
+				t.Errorf("old markdown remains: %v", err)
+			}
+			readMarkdown(t, work, after[2])
+
+			_, err := os.Stat(articleDir(work, &before[1]))
+			if removed := os.IsNotExist(err); removed != tt.wantRemoved {
+				t.Errorf("deleted article removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/repository"
+)
+
+// チーム単位のリソースの保存先 (<dir>/_team)
+// グループのurl_nameと衝突しないよう _ から始める
+//
+//	_team/authenticated_user.json
+//	_team/members.json
+//	_team/invitations.json
+//	_team/groups.json
+//	_team/groups/<url_name>/members.json
+//	_team/tags.json
+//	_team/templates.json
+//	_team/projects/<id>/project.json   コメントを含む
+//	_team/projects/<id>/body.md
+const teamDirName = "_team"
+
+// グループ, チームのメンバー, 招待中のメンバー, タグ, テンプレート, プロジェクト, 認証中のユーザーを保存する
+// 権限がない (招待中のメンバーは管理者のみ) か、エンドポイントがない (qiita.com) リソースはスキップする
+// 失敗したリソースがあっても残りのリソースは保存し、最後にまとめてエラーを返す
+func exportTeam(ctx context.Context, api *repository.QiitaAPI, outputDir string) error {
+	dir := filepath.Join(outputDir, teamDirName)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	var errs []error
+	record := func(name string, count int, err error) {
+		switch {
+		case err == nil:
+			slog.Info("チームのリソースを保存しました", "resource", name, "count", count)
+		case ctx.Err() == nil && (repository.IsForbidden(err) || repository.IsNotFound(err)):
+			slog.Warn("取得できないためスキップします", "resource", name, "error", err)
+		default:
+			errs = append(errs, fmt.Errorf("failed to export %s: %w", name, err))
+		}
+	}
+
+	user, err := api.RequestAuthenticatedUserContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "authenticated_user.json"), user)
+	}
+	record("認証中のユーザー", 1, err)
+
+	members, err := api.RequestTeamMembershipsContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "members.json"), members)
+	}
+	record("チームのメンバー", len(members), err)
+
+	invitations, err := api.RequestTeamInvitationsContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "invitations.json"), invitations)
+	}
+	record("招待中のメンバー", len(invitations), err)
+
+	tags, err := api.RequestTagsContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "tags.json"), tags)
+	}
+	record("タグ", len(tags), err)
+
+	templates, err := api.RequestTemplatesContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "templates.json"), templates)
+	}
+	record("テンプレート", len(templates), err)
+
+	groups, err := api.RequestGroupsContext(ctx)
+	if err == nil {
+		err = writeJSONFile(filepath.Join(dir, "groups.json"), groups)
+	}
+	if err == nil {
+		err = exportGroupMembers(ctx, api, filepath.Join(dir, "groups"), groups)
+	}
+	record("グループ", len(groups), err)
+
+	projects, err := api.RequestProjectsContext(ctx)
+	if err == nil {
+		err = exportProjects(filepath.Join(dir, "projects"), projects)
+	}
+	record("プロジェクト", len(projects), err)
+
+	return errors.Join(errs...)
+}
+
+// グループごとのメンバーを保存する
+// 削除されたグループが残らないよう、全てのグループのメンバーを取得できた場合のみ作り直す
+func exportGroupMembers(ctx context.Context, api *repository.QiitaAPI, groupsDir string, groups []models.Group) error {
+	members := make([][]models.GroupMember, len(groups))
+	for i, g := range groups {
+		m, err := api.RequestGroupMembersContext(ctx, g.URLName)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", g.URLName, err)
+		}
+		members[i] = m
+	}
+
+	if err := os.RemoveAll(groupsDir); err != nil {
+		return err
+	}
+	for i, g := range groups {
+		if err := writeJSONFile(filepath.Join(groupsDir, sanitizeFilename(g.URLName), "members.json"), members[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// プロジェクトをコメントを含むJSONと、本文のMarkdownとして保存する
+func exportProjects(projectsDir string, projects []models.Project) error {
+	if err := os.RemoveAll(projectsDir); err != nil {
+		return err
+	}
+	for _, p := range projects {
+		projectDir := filepath.Join(projectsDir, strconv.Itoa(p.ID))
+		if err := writeJSONFile(filepath.Join(projectDir, "project.json"), p); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(projectDir, "body.md"), []byte(p.Body), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONを整形して保存する, ディレクトリがない場合は作成する
+func writeJSONFile(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b, 0666)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/models"
+	"github.com/qiita_export/qiitafake"
+)
+
+func testTeamFixtures() qiitafake.Fixtures {
+	return qiitafake.Fixtures{
+		AuthenticatedUser: &models.AuthenticatedUser{User: models.User{ID: "alice"}},
+		TeamMemberships:   []models.TeamMembership{{ID: "alice"}, {ID: "bob"}},
+		TeamInvitations:   []models.TeamInvitation{{Email: "carol@example.com"}},
+		Groups:            []models.Group{{Name: "開発", URLName: "dev"}},
+		GroupMembers:      map[string][]models.GroupMember{"dev": {{ID: "alice"}}},
+		Tags:              []models.Tag{{ID: "Go"}},
+		Templates:         []models.Template{{ID: 1, Name: "日報"}},
+		Projects:          []models.Project{{ID: 1, Name: "移行", Body: "# 移行計画"}},
+	}
+}
+
+// JSONファイルを読み込む
+func readJSONFile(t *testing.T, path string, v any) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportTeam(t *testing.T) {
+	server := qiitafake.NewServer(testTeamFixtures())
+	defer server.Close()
+	dir := t.TempDir()
+
+	if err := exportTeam(context.Background(), newTestAPI(t, server), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	teamDir := filepath.Join(dir, teamDirName)
+	var user models.AuthenticatedUser
+	readJSONFile(t, filepath.Join(teamDir, "authenticated_user.json"), &user)
+	if user.ID != "alice" {
+		t.Errorf("authenticated user = %+v", user)
+	}
+	var members []models.TeamMembership
+	readJSONFile(t, filepath.Join(teamDir, "members.json"), &members)
+	if len(members) != 2 {
+		t.Errorf("members = %+v", members)
+	}
+	var groupMembers []models.GroupMember
+	readJSONFile(t, filepath.Join(teamDir, "groups", "dev", "members.json"), &groupMembers)
+	if len(groupMembers) != 1 || groupMembers[0].ID != "alice" {
+		t.Errorf("group members = %+v", groupMembers)
+	}
+	var project models.Project
+	readJSONFile(t, filepath.Join(teamDir, "projects", "1", "project.json"), &project)
+	if project.Name != "移行" {
+		t.Errorf("project = %+v", project)
+	}
+	if b, err := os.ReadFile(filepath.Join(teamDir, "projects", "1", "body.md")); err != nil || string(b) != "# 移行計画" {
+		t.Errorf("body.md = %q, %v", b, err)
+	}
+	for _, name := range []string{"invitations.json", "groups.json", "tags.json", "templates.json"} {
+		if _, err := os.Stat(filepath.Join(teamDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// 権限がない (403) リソースとエンドポイントがない (404) リソースはスキップし、残りのリソースを保存する
+func TestExportTeamSkipsUnavailable(t *testing.T) {
+	fixtures := testTeamFixtures()
+	fixtures.AuthenticatedUser = nil
+	server := qiitafake.NewServer(fixtures)
+	defer server.Close()
+	server.InjectFault(qiitafake.Fault{Path: "/api/v2/team_invitations", Status: http.StatusForbidden})
+	server.InjectFault(qiitafake.Fault{Path: "/api/v2/projects", Status: http.StatusNotFound})
+	dir := t.TempDir()
+
+	if err := exportTeam(context.Background(), newTestAPI(t, server), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	teamDir := filepath.Join(dir, teamDirName)
+	for _, name := range []string{"authenticated_user.json", "invitations.json", "projects"} {
+		if _, err := os.Stat(filepath.Join(teamDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is written: %v", name, err)
+		}
+	}
+	for _, name := range []string{"members.json", "groups.json", "tags.json", "templates.json"} {
+		if _, err := os.Stat(filepath.Join(teamDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// スキップしないエラーで失敗したリソースがあっても残りのリソースは保存し、最後にエラーを返す
+func TestExportTeamError(t *testing.T) {
+	server := qiitafake.NewServer(testTeamFixtures())
+	defer server.Close()
+	server.InjectFault(qiitafake.Fault{Path: "/api/v2/tags", Status: http.StatusBadRequest})
+	dir := t.TempDir()
+
+	err := exportTeam(context.Background(), newTestAPI(t, server), dir)
+	if err == nil || !strings.Contains(err.Error(), "タグ") {
+		t.Fatalf("exportTeam() = %v, want an error for tags", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, teamDirName, "tags.json")); !os.IsNotExist(err) {
+		t.Errorf("tags.json is written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, teamDirName, "projects", "1", "project.json")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/qiita_export/repository"
+)
+
+// 記事のディレクトリ名 (記事ID)
+var articleIDRegexp = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// verifyコマンド
+// 出力ディレクトリの <group>/<id>/ ごとに、メタデータとMarkdownが揃っているか確認する
+// manifest.json がある場合は、記録したファイルと照合して欠落, 追加, 切り詰め, 変更を検出する
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	outputDir := addDirFlag(fs)
+	useManifest := fs.Bool("manifest", true, "manifest.json がある場合、記録したファイルのサイズとSHA-256を照合する")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	problems, articles, err := verifyOutputDir(*outputDir)
+	if err != nil {
+		return err
+	}
+
+	if *useManifest {
+		m, err := loadManifest(*outputDir)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			slog.Warn("マニフェストがないため、ファイルの照合はしません", "file", manifestFileName)
+		case err != nil:
+			return err
+		default:
+			manifestProblems, err := verifyManifest(*outputDir, m)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, manifestProblems...)
+			if m.TotalCount >= 0 {
+				fmt.Printf("APIのTotal-Count: %d件 (query=%q), マニフェストの記事: %d件\n", m.TotalCount, m.Query, len(m.Articles))
+			}
+		}
+	}
+
+	for _, p := range problems {
+		fmt.Println("  " + p)
+	}
+	fmt.Printf("記事: %d件, 問題: %d件\n", articles, len(problems))
+	if b, err := os.ReadFile(filepath.Join(*outputDir, assetReportFileName)); err == nil && len(b) > 0 {
+		slog.Warn("ダウンロードに失敗したアセットがあります", "path", filepath.Join(*outputDir, assetReportFileName))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found in %s", len(problems), *outputDir)
+	}
+	return nil
+}
+
+// 記事のディレクトリを確認し、問題の一覧と記事の数を返す
+// アセットストアや静的サイトのディレクトリなど、記事IDの形式でないディレクトリは対象外とする
+func verifyOutputDir(outputDir string) (problems []string, articles int, err error) {
+	dirs, err := filepath.Glob(filepath.Join(outputDir, "*", "*"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	repo := repository.ArticleMetadata{}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() || !articleIDRegexp.MatchString(filepath.Base(dir)) {
+			continue
+		}
+		articles++
+
+		metadataPaths, err := filepath.Glob(filepath.Join(dir, "*_metadata.json"))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(metadataPaths) != 1 {
+			problems = append(problems, fmt.Sprintf("%s: メタデータが%d件あります", dir, len(metadataPaths)))
+			continue
+		}
+
+		e := repository.ExportedArticle{Dir: dir, MetadataPath: metadataPaths[0]}
+		if e.Article, err = repo.GetArticle(e.MetadataPath); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", e.MetadataPath, err))
+			continue
+		}
+		if e.Article.ID != filepath.Base(dir) {
+			problems = append(problems, fmt.Sprintf("%s: メタデータの記事ID (%s) がディレクトリ名と一致しません", dir, e.Article.ID))
+		}
+		if _, err := os.Stat(e.MarkdownPath()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: Markdownがありません", e.MarkdownPath()))
+		}
+	}
+
+	return problems, articles, nil
+}
+
+// マニフェストに記録したファイルと出力ディレクトリのファイルを照合する
+// 記事のディレクトリにマニフェストにないファイルがある場合は追加として報告する
+func verifyManifest(outputDir string, m *exportManifest) ([]string, error) {
+	var problems []string
+	check := func(f manifestFile) {
+		path := filepath.Join(outputDir, filepath.FromSlash(f.Path))
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("欠落: %s", f.Path))
+			return
+		case info.Size() < f.Size:
+			problems = append(problems, fmt.Sprintf("切り詰め: %s (%d → %d bytes)", f.Path, f.Size, info.Size()))
+			return
+		}
+		if _, sum, err := hashFile(path); err != nil || info.Size() != f.Size || sum != f.SHA256 {
+			problems = append(problems, fmt.Sprintf("変更: %s", f.Path))
+		}
+	}
+
+	known := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, a := range m.Articles {
+		dirs[a.Dir] = true
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(a.Dir))); err != nil {
+			problems = append(problems, fmt.Sprintf("欠落: %s/ (記事 %s)", a.Dir, a.ID))
+			continue
+		}
+		for _, f := range a.Files {
+			known[f.Path] = true
+			check(f)
+		}
+		// 記事のディレクトリ外 (アセットストア) のアセットのみ照合する, ディレクトリ内のアセットはFilesで照合済み
+		for _, asset := range a.Assets {
+			if !known[asset.Path] {
+				known[asset.Path] = true
+				check(asset.manifestFile)
+			}
+		}
+	}
+
+	articleDirs, err := filepath.Glob(filepath.Join(outputDir, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range articleDirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() || !articleIDRegexp.MatchString(filepath.Base(dir)) {
+			continue
+		}
+		rel, err := relSlash(outputDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !dirs[rel] {
+			problems = append(problems, fmt.Sprintf("追加: %s/ (マニフェストにない記事)", rel))
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if p := rel + "/" + e.Name(); !known[p] {
+				problems = append(problems, fmt.Sprintf("追加: %s", p))
+			}
+		}
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qiita_export/qiitafake"
+)
+
+func TestVerify(t *testing.T) {
+	fixtures := testFixtures()
+	server := qiitafake.NewServer(fixtures)
+	defer server.Close()
+	dir := t.TempDir()
+	if err := execute(context.Background(), newTestAPI(t, server), testOptions(dir)); err != nil {
+		t.Fatal(err)
+	}
+	// 出力ディレクトリからの相対パス
+	mdPath := filepath.Join(articleDir("", &fixtures.Articles[0]), sanitizeFilename(fixtures.Articles[0].Title)+".md")
+
+	tests := []struct {
+		name   string
+		tamper func(dir string) error
+		// 出力ディレクトリ, マニフェストの照合で報告する問題 (空の場合は問題なし)
+		wantDir      string
+		wantManifest string
+	}{
+		{
+			name:   "変更なし",
+			tamper: func(string) error { return nil },
+		},
+		{
+			name: "切り詰め",
+			tamper: func(dir string) error {
+				return os.Truncate(filepath.Join(dir, mdPath), 1)
+			},
+			wantManifest: "切り詰め: dev/" + testID1 + "/",
+		},
+		{
+			name: "変更",
+			tamper: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "dev", testID1, "a.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDX"), 0666)
+			},
+			wantManifest: "変更: dev/" + testID1 + "/a.png",
+		},
+		{
+			name: "欠落",
+			tamper: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "dev", testID1, "a.png"))
+			},
+			wantManifest: "欠落: dev/" + testID1 + "/a.png",
+		},
+		{
+			name: "追加",
+			tamper: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, noGroupDirName, testID3, "extra.txt"), nil, 0666)
+			},
+			wantManifest: "追加: " + noGroupDirName + "/" + testID3 + "/extra.txt",
+		},
+		{
+			name: "Markdownがない",
+			tamper: func(dir string) error {
+				return os.Remove(filepath.Join(dir, mdPath))
+			},
+			wantDir:      "Markdownがありません",
+			wantManifest: "欠落: dev/" + testID1 + "/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			work := t.TempDir()
+			if err := os.CopyFS(work, os.DirFS(dir)); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.tamper(work); err != nil {
+				t.Fatal(err)
+			}
+
+			problems, articles, err := verifyOutputDir(work)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if articles != 3 {
+				t.Errorf("articles = %d, want 3", articles)
+			}
+			checkProblems(t, "verifyOutputDir", problems, tt.wantDir)
+
+			m, err := loadManifest(work)
+			if err != nil {
+				t.Fatal(err)
+			}
+			problems, err = verifyManifest(work, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkProblems(t, "verifyManifest", problems, tt.wantManifest)
+		})
+	}
+}
+
+// 問題が1件でwantを含むこと, wantが空の場合は問題がないことを確認する
+func checkProblems(t *testing.T, name string, problems []string, want string) {
+	t.Helper()
+	if want == "" {
+		if len(problems) > 0 {
+			t.Errorf("%s: problems = %v, want none", name, problems)
+		}
+		return
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], want) {
+		t.Errorf("%s: problems = %v, want %q", name, problems, want)
+	}
+}